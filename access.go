@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+var loggerPtrType = reflect.TypeOf((*slog.Logger)(nil))
+
+// Logged for the requests the client gave up, like nginx does
+const statusClientClosed = 499
+
+// Keeps what was written in the response, for the access log
+type accessWriter struct {
+	http.ResponseWriter
+	Status int
+	Bytes  int
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	if w.Status == 0 {
+		w.Status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(data []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.Bytes += n
+	return n, err
+}
+
+// The responses are still streamed when they are logged
+func (w *accessWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Handlers can still take over the connection, like for websockets
+func (w *accessWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("The ResponseWriter doesn't support Hijack")
+}
+
+// Used by http.ResponseController
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// The line written for a request, once it is answered
+type accessLog struct {
+	Logger *slog.Logger
+	Writer *accessWriter
+	Start  time.Time
+
+	// The request attributes, they are known once the handler is found
+	Path    string
+	Handler string
+	IDs     []interface{}
+	Errors  int
+
+	// True if the client gave up before it was answered
+	Canceled bool
+}
+
+// Start the access log of the request, if the Route has a Logger
+// The returned writer should be used to answer the request
+func startAccessLog(w http.ResponseWriter, req *http.Request, ro *Route, start time.Time) (http.ResponseWriter, *accessLog) {
+	logger := ro.options().Logger
+	if logger == nil {
+		return w, nil
+	}
+
+	aw := &accessWriter{ResponseWriter: w}
+	return aw, &accessLog{
+		Logger: logger,
+		Writer: aw,
+		Start:  start,
+		Path:   req.URL.Path,
+	}
+}
+
+// Return the attributes of the request answered by the handler:
+// the path template, the handler and the IDs by the name of its lists
+func (h *handler) logAttrs(ids idMap) (path string, name string, idAttrs []interface{}) {
+
+	// The IDs from the root to the Route
+	lists := []*Route{}
+	for r := h.Route; r != nil; r = r.Parent {
+		if r.Parent != nil && r.Parent.Elem == r {
+			lists = append([]*Route{r}, lists...)
+		}
+	}
+	for _, r := range lists {
+		if id, exist := ids[ptrOfType(r.Value.Type())]; exist {
+			idAttrs = append(idAttrs, slog.String(r.Name, id.Interface().(*ID).String()))
+		}
+	}
+
+	return h.Endpoint.Path, h.Name, idAttrs
+}
+
+// Return the Logger given to the handler and its Init methods,
+// with the attributes of the request
+func requestLogger(base *slog.Logger, req *http.Request, path, name string, ids []interface{}) *slog.Logger {
+	if base == nil {
+		base = slog.Default()
+	}
+	args := []interface{}{
+		slog.String("method", req.Method),
+		slog.String("path", path),
+		slog.String("handler", name),
+	}
+	if len(ids) > 0 {
+		args = append(args, slog.Group("ids", ids...))
+	}
+	return base.With(args...)
+}
+
+// Write the line of the answered request
+// Server errors are logged as errors, the other requests as info
+func (a *accessLog) write(req *http.Request) {
+	status := a.Writer.Status
+	switch {
+	case a.Canceled:
+		status = statusClientClosed
+	case status == 0:
+		status = http.StatusOK
+	}
+
+	level := slog.LevelInfo
+	if status >= 500 {
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", a.Path),
+	}
+	if len(a.Handler) > 0 {
+		attrs = append(attrs, slog.String("handler", a.Handler))
+	}
+	if len(a.IDs) > 0 {
+		attrs = append(attrs, slog.Group("ids", a.IDs...))
+	}
+	attrs = append(attrs,
+		slog.Int("status", status),
+		slog.Int("bytes", a.Writer.Bytes),
+		slog.Duration("duration", time.Since(a.Start)),
+		slog.Int("errors", a.Errors),
+	)
+
+	a.Logger.LogAttrs(req.Context(), level, "request", attrs...)
+}
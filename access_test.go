@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type JournalAPI struct {
+	Entries EntryList
+}
+
+type EntryList []Entry
+
+type Entry struct {
+	Title string
+}
+
+func (e *Entry) Init(logger *slog.Logger) error {
+	logger.Info("loading")
+	return errors.New("Not cached")
+}
+
+func (e *Entry) GET(err error, logger *slog.Logger) *Entry {
+	e.Title = "Monday"
+	return e
+}
+
+// Return the lines logged as JSON, without the time and duration
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestAccessLog(t *testing.T) {
+	route := newTestRoute(t, JournalAPI{})
+
+	logs := &bytes.Buffer{}
+	route.Options = &Options{Logger: newTestLogger(logs)}
+
+	res := serve(t, route, "GET", "/journalapi/entries/7?full=true")
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected the status 200, got %d %s", res.Code, res.Body)
+	}
+	notFound := serve(t, route, "GET", "/journalapi/nothing")
+
+	handler := "(*api.Entry).GET(error, *slog.Logger) *api.Entry"
+	expected := []map[string]interface{}{
+		{"level": "INFO", "msg": "loading", "method": "GET", "path": "/journalapi/entries/{id}",
+			"handler": handler, "ids": map[string]interface{}{"entries": "7"}},
+		{"level": "INFO", "msg": "request", "method": "GET", "path": "/journalapi/entries/{id}",
+			"handler": handler, "ids": map[string]interface{}{"entries": "7"},
+			"status": float64(200), "bytes": float64(res.Body.Len()), "errors": float64(1)},
+		{"level": "INFO", "msg": "request", "method": "GET", "path": "/journalapi/nothing",
+			"status": float64(404), "bytes": float64(notFound.Body.Len()), "errors": float64(0)},
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got\n%s", len(expected), logs)
+	}
+	for i, line := range lines {
+		found := map[string]interface{}{}
+		err := json.Unmarshal([]byte(line), &found)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(found, expected[i]) {
+			t.Errorf("Expected the line\n%v\ngot\n%v", expected[i], found)
+		}
+	}
+}
+
+func TestRequestLoggerWithoutAccessLog(t *testing.T) {
+	route := newTestRoute(t, JournalAPI{})
+
+	logs := &bytes.Buffer{}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(newTestLogger(logs))
+
+	serve(t, route, "GET", "/journalapi/entries/7")
+
+	// Only the line of the Init, the requests are not logged
+	if strings.Count(logs.String(), "\n") != 1 || !strings.Contains(logs.String(), `"msg":"loading"`) {
+		t.Errorf("Expected only the line of the Init, got\n%s", logs)
+	}
+}
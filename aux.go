@@ -22,7 +22,7 @@ var (
 // This method return true if the received type is an context type
 // It means that it doesn't need to be mapped and will be present in the context
 // It also return an error message if user used *http.ResponseWriter or used http.Request
-// Context types include error and []error types
+// Context types include error and []error types, the File and []File sent and the *Meta
 func isContextType(resourceType reflect.Type) bool {
 	// Test if user used *http.ResponseWriter insted of http.ResponseWriter
 	if resourceType.AssignableTo(responseWriterPtrType) {
@@ -32,6 +32,10 @@ func isContextType(resourceType reflect.Type) bool {
 	if resourceType.AssignableTo(requestType) {
 		log.Fatalf("You asked for %s when you should used %s", resourceType, requestPtrType)
 	}
+	// Test if user used api.Meta insted of *api.Meta
+	if resourceType == metaType {
+		log.Fatalf("You asked for %s when you should used %s", metaType, metaPtrType)
+	}
 	// Test if user used ID insted of *ID
 	if resourceType.AssignableTo(idType) {
 		log.Fatalf("You asked for %s when you should used %s", idType, idPtrType)
@@ -41,7 +45,11 @@ func isContextType(resourceType reflect.Type) bool {
 		resourceType.AssignableTo(requestPtrType) ||
 		resourceType.AssignableTo(errorType) ||
 		resourceType.AssignableTo(errorSliceType) ||
-		resourceType == idPtrType
+		resourceType == idPtrType ||
+		resourceType == metaPtrType ||
+		resourceType == loggerPtrType ||
+		resourceType == fileType ||
+		resourceType == fileSliceType
 }
 
 // Return one Ptr to the given Value...
@@ -0,0 +1,490 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Writes the values of a binary format, like MessagePack or CBOR
+// The values are written by encodeBinary following the encoding/json rules
+type binaryWriter interface {
+	Nil()
+	Bool(b bool)
+	Int(i int64)
+	Uint(u uint64)
+	Float32(f float32)
+	Float64(f float64)
+	String(s string)
+	Bytes(b []byte)
+	ArrayHeader(n int)
+	MapHeader(n int)
+}
+
+// Reads the generic values of a binary format:
+// map[string]interface{}, []interface{}, int64, uint64, float64,
+// string, []byte, bool and nil
+type binaryReader interface {
+	Value() (interface{}, error)
+}
+
+// Max nesting of the decoded values, so a small body can't exhaust the stack
+const maxBinaryDepth = 1000
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Write the Value with the binary writer
+// Structs are written as maps with the same names and omitempty of JSON
+func encodeBinary(w binaryWriter, v reflect.Value) error {
+
+	if !v.IsValid() {
+		w.Nil()
+		return nil
+	}
+
+	// Types that know how to write themselves, like time.Time
+	if v.Type().Implements(jsonMarshalerType) && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		data, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return err
+		}
+		generic, err := genericValue(json.RawMessage(data))
+		if err != nil {
+			return err
+		}
+		return encodeGeneric(w, generic)
+	}
+	if v.Type().Implements(textMarshalerType) && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+		w.String(string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			w.Nil()
+			return nil
+		}
+		return encodeBinary(w, v.Elem())
+
+	case reflect.Bool:
+		w.Bool(v.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.Int(v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.Uint(v.Uint())
+
+	case reflect.Float32:
+		w.Float32(float32(v.Float()))
+
+	case reflect.Float64:
+		w.Float64(v.Float())
+
+	case reflect.String:
+		w.String(v.String())
+
+	case reflect.Slice:
+		if v.IsNil() {
+			w.Nil()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			w.Bytes(v.Bytes())
+			return nil
+		}
+		fallthrough
+
+	case reflect.Array:
+		w.ArrayHeader(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			err := encodeBinary(w, v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			w.Nil()
+			return nil
+		}
+		return encodeBinaryMap(w, v)
+
+	case reflect.Struct:
+		return encodeBinaryStruct(w, v)
+
+	default:
+		return fmt.Errorf("Type %s can't be encoded", v.Type())
+	}
+
+	return nil
+}
+
+// Write the map with its keys as sorted strings, like encoding/json does
+func encodeBinaryMap(w binaryWriter, v reflect.Value) error {
+
+	keys := make([]string, 0, v.Len())
+	values := map[string]reflect.Value{}
+
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKeyString(iter.Key())
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	sort.Strings(keys)
+
+	w.MapHeader(len(keys))
+	for _, key := range keys {
+		w.String(key)
+		err := encodeBinary(w, values[key])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write the Struct as a map of its JSON field names
+func encodeBinaryStruct(w binaryWriter, v reflect.Value) error {
+
+	fields := jsonFields(v.Type())
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		fv := fieldValue(v, f)
+		if !fv.IsValid() || f.OmitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		names = append(names, f.Name)
+		values = append(values, fv)
+	}
+
+	w.MapHeader(len(names))
+	for i, name := range names {
+		w.String(name)
+		err := encodeBinary(w, values[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Return the text of a map key, the same way encoding/json does
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.Type().Implements(textMarshalerType) {
+		text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("Map key type %s can't be encoded", k.Type())
+}
+
+// Write the generic value decoded by encoding/json
+func encodeGeneric(w binaryWriter, v interface{}) error {
+	switch value := v.(type) {
+	case nil:
+		w.Nil()
+	case bool:
+		w.Bool(value)
+	case string:
+		w.String(value)
+	case json.Number:
+		i, err := value.Int64()
+		if err == nil {
+			w.Int(i)
+			return nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		w.Float64(f)
+	case []interface{}:
+		w.ArrayHeader(len(value))
+		for _, elem := range value {
+			err := encodeGeneric(w, elem)
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		return encodeBinaryMap(w, reflect.ValueOf(value))
+	default:
+		return encodeBinary(w, reflect.ValueOf(v))
+	}
+	return nil
+}
+
+// Set the generic value read by a binaryReader in the given Value
+// Struct fields are found by its JSON names, like encoding/json does
+func assignGeneric(v reflect.Value, g interface{}) error {
+
+	if g == nil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	// Types that know how to read themselves, like time.Time
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		ptr := v.Addr()
+		if s, ok := g.(string); ok && ptr.Type().Implements(textUnmarshalerType) {
+			return ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		}
+		if ptr.Type().Implements(jsonUnmarshalerType) {
+			data, err := json.Marshal(g)
+			if err != nil {
+				return err
+			}
+			return ptr.Interface().(json.Unmarshaler).UnmarshalJSON(data)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return assignGeneric(v.Elem(), g)
+
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return fmt.Errorf("Can't decode in the interface %s", v.Type())
+		}
+		v.Set(reflect.ValueOf(g))
+		return nil
+
+	case reflect.Struct:
+		m, ok := g.(map[string]interface{})
+		if !ok {
+			return mismatch(v, g)
+		}
+		fields := jsonFields(v.Type())
+		for key, value := range m {
+			f, exist := findJSONField(fields, key)
+			if !exist {
+				continue
+			}
+			err := assignGeneric(settableField(v, f), value)
+			if err != nil {
+				return fmt.Errorf("Field %s: %s", f.Name, err)
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		m, ok := g.(map[string]interface{})
+		if !ok {
+			return mismatch(v, g)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(m)))
+		}
+		for key, value := range m {
+			k := reflect.New(v.Type().Key()).Elem()
+			err := assignGeneric(k, key)
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			err = assignGeneric(elem, value)
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(k, elem)
+		}
+		return nil
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			switch b := g.(type) {
+			case []byte:
+				v.SetBytes(append([]byte{}, b...))
+				return nil
+			case string:
+				v.SetBytes([]byte(b))
+				return nil
+			}
+		}
+		list, ok := g.([]interface{})
+		if !ok {
+			return mismatch(v, g)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, elem := range list {
+			err := assignGeneric(slice.Index(i), elem)
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+
+	case reflect.Array:
+		list, ok := g.([]interface{})
+		if !ok {
+			return mismatch(v, g)
+		}
+		for i := 0; i < v.Len() && i < len(list); i++ {
+			err := assignGeneric(v.Index(i), list[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.String:
+		switch s := g.(type) {
+		case string:
+			v.SetString(s)
+		case []byte:
+			v.SetString(string(s))
+		default:
+			// Map keys are always strings, so numbers should be read from them
+			return mismatch(v, g)
+		}
+		return nil
+
+	case reflect.Bool:
+		b, ok := g.(bool)
+		if !ok {
+			return mismatch(v, g)
+		}
+		v.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := genericInt(g)
+		if !ok || v.OverflowInt(i) {
+			return mismatch(v, g)
+		}
+		v.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := genericInt(g)
+		if ok && i >= 0 {
+			g = uint64(i)
+		}
+		u, ok := g.(uint64)
+		if !ok || v.OverflowUint(u) {
+			return mismatch(v, g)
+		}
+		v.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		switch n := g.(type) {
+		case float64:
+			v.SetFloat(n)
+		case int64:
+			v.SetFloat(float64(n))
+		case uint64:
+			v.SetFloat(float64(n))
+		default:
+			return mismatch(v, g)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("Type %s can't be decoded", v.Type())
+}
+
+// Return the generic number as an int64
+// Map keys and integral floats are accepted too
+func genericInt(g interface{}) (int64, bool) {
+	switch n := g.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt64 && n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// Return the field with the given name
+// If none has exactly this name, it is searched ignoring the case, like encoding/json does
+func findJSONField(fields []jsonField, name string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+func mismatch(v reflect.Value, g interface{}) error {
+	return fmt.Errorf("Can't decode %T in %s", g, v.Type())
+}
+
+// Read a whole value from the reader and set it in the given Ptr
+func decodeBinary(r binaryReader, v interface{}) error {
+	g, err := r.Value()
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("Can't decode in the non Ptr %T", v)
+	}
+	return assignGeneric(value.Elem(), g)
+}
+
+// Used by the binary readers to read strings with a known length
+func readFull(r *bytes.Reader, n uint64) ([]byte, error) {
+	if n > uint64(r.Len()) {
+		return nil, fmt.Errorf("Unexpected end of data, %d bytes missing", n-uint64(r.Len()))
+	}
+	b := make([]byte, n)
+	_, err := r.Read(b)
+	return b, err
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type binaryCodec struct {
+	Encoder Encoder
+	Decoder Decoder
+}
+
+var binaryCodecs = []binaryCodec{
+	{MsgPackEncoder{}, MsgPackDecoder{}},
+	{CBOREncoder{}, CBORDecoder{}},
+}
+
+// Encode the value and decode it back in the given Ptr
+func roundTrip(t *testing.T, codec binaryCodec, v interface{}, ptr interface{}) []byte {
+	buf := &bytes.Buffer{}
+	err := codec.Encoder.Encode(buf, v)
+	if err != nil {
+		t.Fatalf("%s: %s", codec.Encoder.MediaType(), err)
+	}
+	data := buf.Bytes()
+
+	req, _ := http.NewRequest("PUT", "/", bytes.NewReader(data))
+	err = codec.Decoder.Decode(req, ptr)
+	if err != nil {
+		t.Fatalf("%s: %s", codec.Decoder.MediaType(), err)
+	}
+	return data
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	a := A{
+		Id:   "a1",
+		Name: "Testing",
+		Bs: &BList{
+			{Id: 1, Name: "First", Cs: CList{{Id: 10, BId: 1}, {Id: -300, BId: 1, Nothing: "x"}}},
+			{Id: 1 << 40, Name: "Second"},
+		},
+	}
+
+	for _, codec := range binaryCodecs {
+		decoded := A{}
+		roundTrip(t, codec, a, &decoded)
+		if !reflect.DeepEqual(a, decoded) {
+			t.Errorf("%s: expected %+v, got %+v", codec.Encoder.MediaType(), a, decoded)
+		}
+
+		// The json tags name the fields and omitempty drops them
+		books := BookList{
+			{Title: "Dom Casmurro", Price: 10.5, Author: &Author{Name: "Machado"}},
+			{Title: "Untitled"},
+		}
+		generic := []interface{}{}
+		roundTrip(t, codec, books, &generic)
+
+		first := generic[0].(map[string]interface{})
+		if first["title"] != "Dom Casmurro" || first["price"] != 10.5 {
+			t.Errorf("%s: unexpected book %v", codec.Encoder.MediaType(), first)
+		}
+		if _, exist := generic[1].(map[string]interface{})["author"]; exist {
+			t.Errorf("%s: expected the empty author to be omitted", codec.Encoder.MediaType())
+		}
+
+		decodedBooks := BookList{}
+		roundTrip(t, codec, books, &decodedBooks)
+		if !reflect.DeepEqual(books, decodedBooks) {
+			t.Errorf("%s: expected %+v, got %+v", codec.Encoder.MediaType(), books, decodedBooks)
+		}
+	}
+}
+
+func TestBinaryKnownBytes(t *testing.T) {
+	tests := []struct {
+		Encoder  Encoder
+		Value    interface{}
+		Expected []byte
+	}{
+		{MsgPackEncoder{}, map[string]int{"a": 1, "b": -1}, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0xff}},
+		{MsgPackEncoder{}, []interface{}{nil, true, 256}, []byte{0x93, 0xc0, 0xc3, 0xcd, 0x01, 0x00}},
+		{CBOREncoder{}, map[string]int{"a": 1, "b": -1}, []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'b', 0x20}},
+		{CBOREncoder{}, []interface{}{nil, true, 256}, []byte{0x83, 0xf6, 0xf5, 0x19, 0x01, 0x00}},
+	}
+
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		err := test.Encoder.Encode(buf, test.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), test.Expected) {
+			t.Errorf("%s %v: expected % x, got % x", test.Encoder.MediaType(), test.Value, test.Expected, buf.Bytes())
+		}
+	}
+
+	// Indefinite lengths and half floats sent by other CBOR encoders
+	v := map[string]interface{}{}
+	req, _ := http.NewRequest("PUT", "/", bytes.NewReader([]byte{
+		0xbf, 0x61, 'a', 0x9f, 0xf9, 0x3e, 0x00, 0xff, 0x61, 'b', 0x7f, 0x61, 'x', 0x61, 'y', 0xff, 0xff,
+	}))
+	err := CBORDecoder{}.Decode(req, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"a": []interface{}{1.5}, "b": "xy"}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected %v, got %v", expected, v)
+	}
+
+	// Headers bigger than the data are rejected before allocating
+	req, _ = http.NewRequest("PUT", "/", bytes.NewReader([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}))
+	err = MsgPackDecoder{}.Decode(req, &v)
+	if err == nil {
+		t.Error("Expected an error for the truncated array")
+	}
+}
+
+func TestBinaryNegotiation(t *testing.T) {
+	for _, codec := range binaryCodecs {
+		route := newTestRoute(t, ShopAPI{})
+
+		res := serveAccept(t, route, "/shopapi/books", codec.Encoder.MediaType())
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", codec.Encoder.MediaType(), http.StatusOK, res.Code, res.Body)
+		}
+		if res.Header().Get("Content-Type") != codec.Encoder.MediaType() {
+			t.Errorf("Expected Content-Type %s, got %s", codec.Encoder.MediaType(), res.Header().Get("Content-Type"))
+		}
+
+		books := BookList{}
+		req, _ := http.NewRequest("PUT", "/", res.Body)
+		err := codec.Decoder.Decode(req, &books)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(books) != 2 || books[0].Author == nil || books[0].Author.Name != "Machado" {
+			t.Errorf("%s: unexpected books %+v", codec.Encoder.MediaType(), books)
+		}
+
+		// The request body is decoded by its Content-Type
+		body := &bytes.Buffer{}
+		codec.Encoder.Encode(body, Member{Name: "Ana", Age: 30, Address: &Address{City: "Recife"}})
+
+		member := newTestRoute(t, MemberAPI{})
+		res = httptest.NewRecorder()
+		req, _ = http.NewRequest("PUT", "/memberapi/member", body)
+		req.Header.Set("Content-Type", codec.Decoder.MediaType())
+		req.Header.Set("Accept", codec.Encoder.MediaType())
+		member.ServeHTTP(res, req)
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", codec.Decoder.MediaType(), http.StatusOK, res.Code, res.Body)
+		}
+
+		m := Member{}
+		req, _ = http.NewRequest("PUT", "/", res.Body)
+		err = codec.Decoder.Decode(req, &m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Name != "Ana" || m.Age != 30 || m.Address == nil || m.Address.City != "Recife" {
+			t.Errorf("%s: unexpected member %+v", codec.Decoder.MediaType(), m)
+		}
+	}
+}
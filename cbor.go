@@ -0,0 +1,378 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+)
+
+// Encodes the responses in CBOR, RFC 8949
+// Structs follow the same rules of JSON, its json tags and omitempty
+type CBOREncoder struct{}
+
+func (e CBOREncoder) MediaType() string {
+	return "application/cbor"
+}
+
+func (e CBOREncoder) Encode(w io.Writer, v interface{}) error {
+	buf := &cborWriter{}
+	err := encodeBinary(buf, reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Buffer.Bytes())
+	return err
+}
+
+// Decodes the request bodies in CBOR
+// Tags are ignored, only its content is decoded
+type CBORDecoder struct{}
+
+func (d CBORDecoder) MediaType() string {
+	return "application/cbor"
+}
+
+func (d CBORDecoder) Decode(req *http.Request, v interface{}) error {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return decodeBinary(&cborReader{Reader: bytes.NewReader(data)}, v)
+}
+
+// The major types of the CBOR data items
+const (
+	cborUint   = 0 << 5
+	cborNegint = 1 << 5
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborTag    = 6 << 5
+	cborSimple = 7 << 5
+)
+
+// Writes the CBOR data items always with definite lengths
+type cborWriter struct {
+	Buffer bytes.Buffer
+}
+
+func (w *cborWriter) Nil() {
+	w.Buffer.WriteByte(cborSimple | 22)
+}
+
+func (w *cborWriter) Bool(b bool) {
+	if b {
+		w.Buffer.WriteByte(cborSimple | 21)
+	} else {
+		w.Buffer.WriteByte(cborSimple | 20)
+	}
+}
+
+func (w *cborWriter) Int(i int64) {
+	if i >= 0 {
+		w.header(cborUint, uint64(i))
+	} else {
+		w.header(cborNegint, uint64(-1-i))
+	}
+}
+
+func (w *cborWriter) Uint(u uint64) {
+	w.header(cborUint, u)
+}
+
+func (w *cborWriter) Float32(f float32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], math.Float32bits(f))
+	w.Buffer.WriteByte(cborSimple | 26)
+	w.Buffer.Write(b[:])
+}
+
+func (w *cborWriter) Float64(f float64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+	w.Buffer.WriteByte(cborSimple | 27)
+	w.Buffer.Write(b[:])
+}
+
+func (w *cborWriter) String(s string) {
+	w.header(cborText, uint64(len(s)))
+	w.Buffer.WriteString(s)
+}
+
+func (w *cborWriter) Bytes(b []byte) {
+	w.header(cborBytes, uint64(len(b)))
+	w.Buffer.Write(b)
+}
+
+func (w *cborWriter) ArrayHeader(n int) {
+	w.header(cborArray, uint64(n))
+}
+
+func (w *cborWriter) MapHeader(n int) {
+	w.header(cborMap, uint64(n))
+}
+
+// Write the major type with its argument in the smallest form
+func (w *cborWriter) header(major byte, n uint64) {
+	var b [8]byte
+	switch {
+	case n < 24:
+		w.Buffer.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		w.Buffer.WriteByte(major | 24)
+		w.Buffer.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.Buffer.WriteByte(major | 25)
+		binary.BigEndian.PutUint16(b[:], uint16(n))
+		w.Buffer.Write(b[:2])
+	case n <= math.MaxUint32:
+		w.Buffer.WriteByte(major | 26)
+		binary.BigEndian.PutUint32(b[:], uint32(n))
+		w.Buffer.Write(b[:4])
+	default:
+		w.Buffer.WriteByte(major | 27)
+		binary.BigEndian.PutUint64(b[:], n)
+		w.Buffer.Write(b[:])
+	}
+}
+
+// Reads CBOR data items as generic values
+// Indefinite lengths are accepted too
+type cborReader struct {
+	*bytes.Reader
+	depth int
+}
+
+// The argument of the items with indefinite length
+const cborIndefinite = 31
+
+// The byte that ends the items with indefinite length
+const cborBreak = 0xff
+
+func (r *cborReader) Value() (interface{}, error) {
+
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > maxBinaryDepth {
+		return nil, fmt.Errorf("The data is nested more than %d levels", maxBinaryDepth)
+	}
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	major, info := b&0xe0, b&0x1f
+
+	// The simple values and the floats use the argument by themselves
+	if major == cborSimple {
+		return r.readSimple(info)
+	}
+
+	if info == cborIndefinite {
+		return r.readIndefinite(major)
+	}
+
+	n, err := r.argument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		return n, nil
+
+	case cborNegint:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("The negative integer -1-%d doesn't fit in an int64", n)
+		}
+		return -1 - int64(n), nil
+
+	case cborBytes:
+		return readFull(r.Reader, n)
+
+	case cborText:
+		text, err := readFull(r.Reader, n)
+		return string(text), err
+
+	case cborArray:
+		// Each item has at least one byte, so the data can't have more than it
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i], err = r.Value()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+
+	case cborMap:
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			err = r.readPair(m)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case cborTag:
+		// Like the epoch of times, only the tagged content matters here
+		return r.Value()
+	}
+
+	return nil, fmt.Errorf("CBOR major type %d is not supported", major>>5)
+}
+
+// Read the argument that follows the initial byte
+func (r *cborReader) argument(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	if info > 27 {
+		return 0, fmt.Errorf("CBOR additional information %d is not supported", info)
+	}
+	b, err := readFull(r.Reader, 1<<(info-24))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+// Read the values of the major type 7: booleans, null and floats
+func (r *cborReader) readSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null and undefined
+		return nil, nil
+	case 25:
+		n, err := r.argument(info)
+		return halfFloat(uint16(n)), err
+	case 26:
+		n, err := r.argument(info)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 27:
+		n, err := r.argument(info)
+		return math.Float64frombits(n), err
+	}
+	return nil, fmt.Errorf("CBOR simple value %d is not supported", info)
+}
+
+// Read the items with indefinite length until the break byte
+// Strings are sent in chunks of the same major type
+func (r *cborReader) readIndefinite(major byte) (interface{}, error) {
+
+	chunks := []byte{}
+	list := []interface{}{}
+	m := map[string]interface{}{}
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if b == cborBreak {
+			break
+		}
+		r.UnreadByte()
+
+		switch major {
+		case cborBytes, cborText:
+			if b&0xe0 != major || b&0x1f == cborIndefinite {
+				return nil, fmt.Errorf("Invalid chunk in a CBOR string")
+			}
+			chunk, err := r.Value()
+			if err != nil {
+				return nil, err
+			}
+			switch c := chunk.(type) {
+			case []byte:
+				chunks = append(chunks, c...)
+			case string:
+				chunks = append(chunks, c...)
+			}
+
+		case cborArray:
+			v, err := r.Value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+
+		case cborMap:
+			err = r.readPair(m)
+			if err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, fmt.Errorf("CBOR major type %d can't have an indefinite length", major>>5)
+		}
+	}
+
+	switch major {
+	case cborBytes:
+		return chunks, nil
+	case cborText:
+		return string(chunks), nil
+	case cborArray:
+		return list, nil
+	}
+	return m, nil
+}
+
+// Read a key and its value into the map
+func (r *cborReader) readPair(m map[string]interface{}) error {
+	k, err := r.Value()
+	if err != nil {
+		return err
+	}
+	key, err := genericKey(k)
+	if err != nil {
+		return err
+	}
+	m[key], err = r.Value()
+	return err
+}
+
+// Convert an IEEE 754 half precision float to a float64
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
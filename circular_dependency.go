@@ -1,9 +1,10 @@
 package api
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 type circularDependency struct {
@@ -21,9 +22,24 @@ func checkCircularDependency(ro *Route) error {
 }
 
 func (cd *circularDependency) checkRoute(ro *Route) error {
-	for _, h := range ro.Handlers {
+	// Handlers and children are maps, walking their keys in order
+	// reports the same cycle on every run
+	keys := []string{}
+	for k := range ro.Handlers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		h := ro.Handlers[k]
 		//log.Println("Check CD for Method", h.Method)
-		for _, d := range h.Dependencies {
+		// Every dependency is reached from the handler inputs,
+		// checking them in order always finds the same cycle
+		for _, t := range h.Method.Inputs {
+			d, exist := h.Dependencies.vaueOf(t)
+			if !exist {
+				continue // Context types
+			}
 			err := cd.checkDependency(d, h)
 			if err != nil {
 				return err
@@ -31,8 +47,14 @@ func (cd *circularDependency) checkRoute(ro *Route) error {
 		}
 	}
 
-	for _, child := range ro.Children {
-		err := cd.checkRoute(child)
+	names := []string{}
+	for name := range ro.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err := cd.checkRoute(ro.Children[name])
 		if err != nil {
 			return err
 		}
@@ -104,24 +126,13 @@ func (cd *circularDependency) checkDependency(d *dependency, h *handler) error {
 func (cd *circularDependency) addAndCheck(t reflect.Type) error {
 
 	// Check for circular dependency
-	ok := true
-	errMsg := ""
-
-	for _, t2 := range cd.Dependents {
-		if !ok {
-			errMsg += fmt.Sprintf("%s that depends on ", t2)
-		}
+	for i, t2 := range cd.Dependents {
 		if t == t2 {
-			ok = false
-			errMsg += fmt.Sprintf("%s depends on ", t)
+			cycle := append([]reflect.Type{}, cd.Dependents[i:]...)
+			return &CircularDependencyError{Cycle: append(cycle, t)}
 		}
 	}
 
-	if !ok {
-		errMsg += fmt.Sprintf("%s\n", t)
-		return errors.New(errMsg)
-	}
-
 	//log.Println("Adding:", t)
 
 	// Everything ok, add this new type dependency
@@ -129,6 +140,35 @@ func (cd *circularDependency) addAndCheck(t reflect.Type) error {
 	return nil
 }
 
+// The dependencies whose Init methods depend on each other
+// The Cycle starts and ends with the same Type
+type CircularDependencyError struct {
+	Cycle []reflect.Type
+}
+
+func (e *CircularDependencyError) Error() string {
+	errMsg := fmt.Sprintf("%s depends on ", e.Cycle[0])
+	for _, t := range e.Cycle[1 : len(e.Cycle)-1] {
+		errMsg += fmt.Sprintf("%s that depends on ", t)
+	}
+	return errMsg + fmt.Sprintf("%s\n", e.Cycle[len(e.Cycle)-1])
+}
+
+// Return the cycle drawn in the format, to see the wiring that causes it
+func (e *CircularDependencyError) Graph(format GraphFormat) string {
+	g := &graph{}
+	for _, t := range e.Cycle[:len(e.Cycle)-1] {
+		g.node(t.String())
+	}
+	for i := range g.Nodes {
+		g.edge(i, (i+1)%len(g.Nodes), "depends on", false)
+	}
+
+	buf := &strings.Builder{}
+	g.write(buf, format)
+	return buf.String()
+}
+
 // Remove the last element from the Dependents list
 func (cd *circularDependency) pop() {
 	//log.Println("Removing:", cd.Dependents[len(cd.Dependents)-1])
@@ -1,130 +1,147 @@
 package api
 
 import (
-	"log"
+	stdcontext "context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"reflect"
+	"sync"
+	"time"
 )
 
 type context struct {
 	Handler *handler
-	Values  []reflect.Value
+	Request *http.Request
+	Values  []reflect.Value // Indexed by the slots of the Handler plan
 	IDMap   idMap
 	Errors  []reflect.Value // To append the errors outputed
+	Files   []File          // The Files sent in a multipart request
+	Decoder Decoder         // Reads the request body in the resource of the handler, nil if it isn't bound
+	Meta    *Meta           // The entries sent in the envelope of the response
+
+	// The Init error that stopped the chain
+	// When it is set, no other method should be called
+	Abort error
+
+	// How long each Init method took, indexed by the slots, when the Route is timed
+	Timed           bool
+	Durations       []time.Duration
+	Ran             []bool
+	HandlerDuration time.Duration
+	HandlerRan      bool
 }
 
 // Creates a new context
 // It creates the initial state used to answer the request
 // Since states are not allowed to be stored on te server,
 // this initial state is all the service has to answer a request
-func newContext(handler *handler, w http.ResponseWriter, req *http.Request, ids idMap) *context {
-	return &context{
+func newContext(handler *handler, w http.ResponseWriter, req *http.Request, ids idMap, meta *Meta, logger *slog.Logger) *context {
+	c := &context{
 		Handler: handler,
-		Values: []reflect.Value{
-			reflect.ValueOf(w),
-			reflect.ValueOf(req),
-		},
-		IDMap:  ids,
-		Errors: []reflect.Value{},
+		Request: req,
+		Values:  make([]reflect.Value, handler.Plan.Slots),
+		IDMap:   ids,
+		Errors:  []reflect.Value{},
+		Meta:    meta,
 	}
-}
-
-func (c *context) run() []reflect.Value {
-
-	//log.Println("Running Context Handler Method:", c.Handler.Method.Method.Type)
-
-	// Then run the main method
-	inputs := c.getInputs(c.Handler.Method)
-
-	return c.Handler.Method.Method.Func.Call(inputs)
-}
-
-// Return the inputs Values from a Method
-// For the especial case of the ID input, we should know the requester Type
-func (c *context) getInputs(m *method) []reflect.Value {
-
-	inputs := m.Inputs
-
-	requester := m.Owner // Get the requester Type
-
-	values := make([]reflect.Value, len(inputs))
-
-	//log.Println("Getting inputs:", inputs)
-
-	for i, t := range inputs {
-
-		//log.Println("Getting input", t)
-		values[i] = c.valueOf(t, requester)
-		//log.Println("Getted", values[i], "for", t)
 
+	if handler.Route.options().timed() {
+		c.Timed = true
+		c.Durations = make([]time.Duration, handler.Plan.Slots)
+		c.Ran = make([]bool, handler.Plan.Slots)
 	}
 
-	//log.Println("Returning values:", values, "for", inputs)
+	c.Values[writerSlot] = reflect.ValueOf(w)
+	c.Values[requestSlot] = reflect.ValueOf(req)
+	c.Values[metaSlot] = reflect.ValueOf(meta)
+	c.Values[loggerSlot] = reflect.ValueOf(logger)
 
-	return values
+	return c
 }
 
-// Get the reflect.Value for the required type
-func (c *context) valueOf(t reflect.Type, requester reflect.Type) reflect.Value {
+// Run the Handler method constructing all its dependencies
+// It returns an error if some Init method stopped the chain
+func (c *context) run() ([]reflect.Value, error) {
 
-	//log.Println("Searching for", t)
+	// Read the Files once, before any method asks for them
+	if c.Handler.Plan.Files {
+		c.Files = requestFiles(c.Request)
+	}
 
-	// If it is requesting the first error in the list
-	if t == errorType {
-		return c.errorValue()
+	// The Decoder is chosen before any Init is called,
+	// so an unsupported body doesn't construct anything
+	err := c.chooseDecoder()
+	if err != nil {
+		return nil, err
 	}
 
-	// If it is requesting the whole error list
-	if t == errorSliceType {
-		return c.errorSliceValue()
+	// Construct the independent dependencies at the same time
+	// Otherwise they are constructed one by one in the plan order
+	if c.Handler.Route.options().Parallel {
+		c.buildParallel()
+	} else {
+		c.build()
 	}
 
-	// If it is requesting the *ID type
-	if t == idPtrType {
-		return c.idValue(requester)
+	// Some dependency failed, the handler can't run on half-built state
+	if c.Abort != nil {
+		return nil, c.Abort
 	}
 
-	// So it can only be a Resource Value
-	// Or Request or Writer
-	v := c.resourceValue(t)
+	// The request body updates the resource constructed by its Init
+	err = c.bind()
+	if err != nil {
+		return nil, err
+	}
 
-	// If it is requiring the Elem itself and it returned a Ptr to Elem
-	if t.Kind() == reflect.Struct && v.Kind() == reflect.Ptr {
-		// It is requiring the Elem of a nil Ptr?
-		// Ok, give it an empty Elem of that Type
-		if v.IsNil() {
-			return reflect.New(t).Elem()
-		}
+	// Then run the main method
+	inputs := c.inputs(c.Handler.Plan.Args)
 
-		return v.Elem()
-		//log.Println("Transformed", v, "for", t)
+	if !c.Timed {
+		return c.Handler.Method.Method.Func.Call(inputs), nil
 	}
 
-	return v
+	start := time.Now()
+	output := c.Handler.Method.Method.Func.Call(inputs)
+	c.HandlerDuration = time.Since(start)
+	c.HandlerRan = true
+
+	return output, nil
 }
 
-// Get the Resource Value of the required Resource Type
-// It could be http.ResponseWriter or *http.Request too
-func (c *context) resourceValue(t reflect.Type) reflect.Value {
-	for _, v := range c.Values {
-		switch t.Kind() {
-		case reflect.Interface:
-			if v.Type().Implements(t) {
-				return v
-			}
-		case reflect.Struct, reflect.Slice: // non-pointer
-			if v.Type().Elem() == t {
-				return v
-			}
-		case reflect.Ptr:
-			if v.Type() == t {
-				return v
+// Return the Values of the given arguments
+func (c *context) inputs(args []argument) []reflect.Value {
+
+	values := make([]reflect.Value, len(args))
+
+	for i, arg := range args {
+		switch arg.Kind {
+		case slotArg:
+			values[i] = c.Values[arg.Slot]
+		case elemArg:
+			v := c.Values[arg.Slot]
+			// It is requiring the Elem of a nil Ptr?
+			// Ok, give it an empty Elem of that Type
+			if v.IsNil() {
+				values[i] = reflect.New(v.Type().Elem()).Elem()
+			} else {
+				values[i] = v.Elem()
 			}
+		case errorArg:
+			values[i] = c.errorValue()
+		case errorSliceArg:
+			values[i] = c.errorSliceValue()
+		case idArg:
+			values[i] = c.idValue(arg.ID)
+		case fileArg:
+			values[i] = c.fileValue()
+		case fileSliceArg:
+			values[i] = reflect.ValueOf(c.Files)
 		}
-
 	}
-	// It is not present yet, so we need to construct it
-	return c.initDependencie(t)
+
+	return values
 }
 
 // Return the first error of the list, or an nil error
@@ -157,69 +174,194 @@ func (c *context) idValue(t reflect.Type) reflect.Value {
 	return nilIDValue
 }
 
-// Construct all the dependencies level by level
-// Garants that every dependencie exists before be requisited
-func (c *context) initDependencie(t reflect.Type) reflect.Value {
+// Return the first File sent, or an empty File
+func (c *context) fileValue() reflect.Value {
+	if len(c.Files) > 0 {
+		return reflect.ValueOf(c.Files[0])
+	}
+	return reflect.ValueOf(File{})
+}
+
+// Choose the Decoder of the request body, if the resource of the handler is bound from it
+// A body no Decoder reads is answered as 415, unless some method reads the request itself
+func (c *context) chooseDecoder() error {
+	if !c.Handler.Plan.Body || !hasBody(c.Request) {
+		return nil
+	}
+
+	d, err := decoderFor(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		if c.Handler.Plan.Raw {
+			return nil
+		}
+		return err
+	}
+
+	c.Decoder = d
+	return nil
+}
+
+// Decode the request body in the resource that owns the handler
+// The first argument of the handler is always its owner
+func (c *context) bind() error {
+	if c.Decoder == nil {
+		return nil
+	}
 
-	dependencie, exist := c.Handler.Dependencies[t]
-	if !exist { // It should never occours
-		log.Panicf("Dependencie %s not mapped!!!", t)
+	owner := c.Values[c.Handler.Plan.Args[0].Slot]
+	if owner.IsNil() { // Its Init returned a nil resource
+		return nil
 	}
+	return decodeBody(c.Decoder, c.Request, owner.Interface())
+}
 
-	//log.Println("Constructing dependency", dependencie.Value.Type())
+// Construct all the dependencies one by one in the plan order
+// Garants that every dependencie exists before be requisited
+func (c *context) build() {
+	for _, s := range c.Handler.Plan.Steps {
+		c.appendErrors(c.construct(s))
+		if c.Abort != nil {
+			return
+		}
+	}
+}
 
-	// This Value will be mapped in the index index
-	index := len(c.Values)
+// Construct all the dependencies level by level
+// Dependencies in the same level are constructed at the same time
+// The errors are added in the plan order, so it is deterministic
+func (c *context) buildParallel() {
 
-	// Instanciate a new dependency and add it to the list
-	c.Values = append(c.Values, dependencie.init())
+	for _, level := range c.Handler.Plan.Levels {
 
-	if dependencie.Method != nil {
+		errs := make([][]reflect.Value, len(level))
 
-		inputs := c.getInputs(dependencie.Method) //dependencie.Input, dependencie.Value.Type())
+		// Each step writes only in its own slot
+		// No Init is started once the client gave up
+		var wg sync.WaitGroup
+		for i, s := range level {
+			c.Abort = c.canceled()
+			if c.Abort != nil {
+				break
+			}
 
-		out := make([]reflect.Value, dependencie.Method.Method.Type.NumOut())
+			// There is nothing to construct at the same time
+			if len(level) == 1 {
+				errs[i] = c.construct(s)
+				continue
+			}
 
-		//log.Printf("Calling %s with %q \n", dependencie.Method.Method.Type, inputs)
+			wg.Add(1)
+			go func(i int, s *step) {
+				defer wg.Done()
+				errs[i] = c.construct(s)
+			}(i, s)
+		}
+		wg.Wait()
 
-		out = dependencie.Method.Method.Func.Call(inputs)
+		if c.Abort != nil {
+			return
+		}
 
-		// If the Init method return something,
-		// it will be the resource itself with
-		// its values updated
-		if dependencie.Method.NumOut > 0 {
+		for _, e := range errs {
+			c.appendErrors(e)
+		}
 
-			for i := 0; i < dependencie.Method.NumOut; i++ {
+		if c.Abort != nil {
+			return
+		}
+	}
+}
 
-				//log.Println("### Threating output:", dependencie.Method.Outputs[i])
+// Return why the request can't go on, nil while it can
+// A client that gave up is answered by nobody, so its error is returned as is
+// A request past its deadline is answered as 503
+func (c *context) canceled() error {
+	err := c.Request.Context().Err()
+	if err == nil || errors.Is(err, stdcontext.Canceled) {
+		return err
+	}
+	return HTTPError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    "timeout",
+		Message: err.Error(),
+	}
+}
 
-				if dependencie.Method.Outputs[i] == errorType {
-					//log.Println("### Fucking shit error!!!!", out[i].IsNil(), out[i].IsValid(), out[i].CanSet(), out[i].CanInterface())
-					if !out[i].IsNil() {
-						c.Errors = append(c.Errors, out[i])
-						//log.Println("### Appending the error!!!!")
-					}
-					continue
-				}
-				// Check if this output is the dependency itself
-				if dependencie.isType(dependencie.Method.Outputs[i]) {
-					//log.Println("### Its just me...", out[i].Type(), out[i].IsValid(), out[i].CanSet(), out[i].CanInterface(), out[i].Type())
+// Instanciate a new dependency in its slot and call its Init method
+// Return the errors the Init outputed
+// It only changes the slot of this step, so the steps of the same level
+// can be constructed at the same time
+func (c *context) construct(s *step) []reflect.Value {
 
-					// If this method outputs an Elem insted an Ptr to the Elem
-					if dependencie.Method.Outputs[i].Kind() != reflect.Ptr {
-						value := reflect.New(dependencie.Method.Outputs[i])
-						value.Elem().Set(out[i])
-						c.Values[index] = value
-					} else {
-						c.Values[index] = out[i]
-					}
-				}
+	c.Values[s.Slot] = s.Dependency.init()
 
-			}
+	if s.Dependency.Method == nil {
+		return nil
+	}
+
+	inputs := c.inputs(s.Args)
+
+	var out []reflect.Value
+	if c.Timed {
+		start := time.Now()
+		out = s.Dependency.Method.Method.Func.Call(inputs)
+		c.Durations[s.Slot] = time.Since(start)
+		c.Ran[s.Slot] = true
+	} else {
+		out = s.Dependency.Method.Method.Func.Call(inputs)
+	}
+
+	// If the Init method return the resource itself,
+	// it will be stored with its values updated
+	if s.Self >= 0 {
+		// If this method outputs an Elem insted an Ptr to the Elem
+		if s.SelfElem {
+			value := reflect.New(out[s.Self].Type())
+			value.Elem().Set(out[s.Self])
+			c.Values[s.Slot] = value
+		} else {
+			c.Values[s.Slot] = out[s.Self]
+		}
+	}
+
+	var errs []reflect.Value
+	for _, i := range s.Errors {
+		if !out[i].IsNil() {
+			errs = append(errs, out[i])
 		}
 	}
 
-	//log.Println("Constructed", c.Values[index], "for", t, "value", c.Values[index].Interface())
+	return errs
+}
+
+// Add the Init errors to the context,
+// stopping the chain if needed
+func (c *context) appendErrors(errs []reflect.Value) {
+	for _, err := range errs {
+		c.Errors = append(c.Errors, err)
+
+		if c.shouldAbort(err.Interface().(error)) {
+			c.Abort = err.Interface().(error)
+		}
+	}
+}
+
+// Return true if this Init error should stop the chain
+// It depends on the Route policy, the error type and if someone asks for the errors
+func (c *context) shouldAbort(err error) bool {
+	if c.Abort != nil {
+		return false // The first error already stopped it
+	}
+
+	if c.Handler.Route.options().InitErrors == IgnoreInitErrors {
+		return false
+	}
+
+	// An HTTPError decides the response by itself,
+	// even if the handler asks for the errors
+	if _, ok := asHTTPError(err); ok {
+		return true
+	}
 
-	return c.Values[index]
+	return !c.Handler.ConsumesErrors
 }
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"bytes"
+	stdcontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type InitAPI struct {
+	Failing  Failing
+	Watching Watching
+	Rows     RowList
+	Missing  Missing
+	Lookup   Lookup
+}
+
+type Failing struct {
+	Name string
+}
+
+func (f *Failing) Init() error {
+	return errors.New("Failing Init")
+}
+
+func (f *Failing) GET() *Failing {
+	f.Name = "Handler ran"
+	return f
+}
+
+type Watching struct {
+	Name string
+}
+
+func (w *Watching) GET(f Failing, err error) *Watching {
+	w.Name = err.Error()
+	return w
+}
+
+// Its Init returns a nil resource with the error
+type Missing struct {
+	Name string
+}
+
+func (m *Missing) Init() (*Missing, error) {
+	return nil, errors.New("Missing Init")
+}
+
+type Lookup struct {
+	Name  string
+	Error string
+}
+
+func (l *Lookup) GET(m Missing, err error) *Lookup {
+	l.Name = m.Name
+	l.Error = err.Error()
+	return l
+}
+
+type RowList []Row
+
+type Row struct {
+	Name string
+}
+
+func (r *Row) Init(id *ID) error {
+	if id == nil || id.String() != "1" {
+		return fmt.Errorf("Row %s: %w", id, ErrNotFound)
+	}
+	r.Name = "Row 1"
+	return nil
+}
+
+func (r *Row) GET(err error) *Row {
+	return r
+}
+
+func serve(t *testing.T, ro *Route, method, uri string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ro.ServeHTTP(res, req)
+	return res
+}
+
+func newTestRoute(t *testing.T, object interface{}) *Route {
+	resource, err := NewResource(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	route, err := NewRoute(resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return route
+}
+
+func TestInitErrorPolicy(t *testing.T) {
+	route := newTestRoute(t, InitAPI{})
+
+	res := serve(t, route, "GET", "/initapi/failing")
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d when Init fails, got %d: %s",
+			http.StatusInternalServerError, res.Code, res.Body)
+	}
+
+	// The handler asks for the error, so it should run
+	res = serve(t, route, "GET", "/initapi/watching")
+	if res.Code != http.StatusOK {
+		t.Errorf("Expected status %d when the error is consumed, got %d: %s",
+			http.StatusOK, res.Code, res.Body)
+	}
+
+	route.Children["failing"].Options = &Options{InitErrors: IgnoreInitErrors}
+
+	res = serve(t, route, "GET", "/initapi/failing")
+	if res.Code != http.StatusOK {
+		t.Errorf("Expected status %d when Init errors are ignored, got %d: %s",
+			http.StatusOK, res.Code, res.Body)
+	}
+}
+
+func TestInitNilResource(t *testing.T) {
+	route := newTestRoute(t, InitAPI{})
+
+	// The handler receives an empty Missing instead of the nil one
+	res := serve(t, route, "GET", "/initapi/lookup")
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, res.Code, res.Body)
+	}
+
+	l := Lookup{}
+	err := json.Unmarshal(res.Body.Bytes(), &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Name != "" || l.Error != "Missing Init" {
+		t.Errorf("Unexpected lookup %+v", l)
+	}
+}
+
+func TestInitHTTPError(t *testing.T) {
+	route := newTestRoute(t, InitAPI{})
+
+	res := serve(t, route, "GET", "/initapi/rows/1")
+	if res.Code != http.StatusOK {
+		t.Errorf("Expected status %d for an existing row, got %d: %s",
+			http.StatusOK, res.Code, res.Body)
+	}
+
+	// The handler asks for the error, but ErrNotFound decides the response
+	res = serve(t, route, "GET", "/initapi/rows/2")
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d for a missing row, got %d: %s",
+			http.StatusNotFound, res.Code, res.Body)
+	}
+
+	body := map[string]string{}
+	err := json.Unmarshal(res.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["code"] != ErrNotFound.Code {
+		t.Errorf("Expected code %q, got %q", ErrNotFound.Code, body["code"])
+	}
+	// The message keeps the context wrapping ErrNotFound
+	expected := "Row 2: " + ErrNotFound.Message
+	if body["error"] != expected {
+		t.Errorf("Expected error %q, got %q", expected, body["error"])
+	}
+}
+
+type ParallelAPI struct {
+	Report Report
+}
+
+// Each part waits for the others, it only finishes in time
+// if all of them are constructed at the same time
+var started int32
+
+func waitOthers() string {
+	atomic.AddInt32(&started, 1)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(&started) >= 3 {
+			return "together"
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return "alone"
+}
+
+type PartA struct{ Status string }
+type PartB struct{ Status string }
+type PartC struct{ Status string }
+
+func (p *PartA) Init() error {
+	p.Status = waitOthers()
+	return errors.New("PartA failed")
+}
+
+func (p *PartB) Init() error {
+	p.Status = waitOthers()
+	return errors.New("PartB failed")
+}
+
+func (p *PartC) Init() {
+	p.Status = waitOthers()
+}
+
+type Report struct {
+	Parts  []string
+	Errors []string
+}
+
+func (r *Report) GET(a PartA, b PartB, c PartC, errs []error) *Report {
+	r.Parts = []string{a.Status, b.Status, c.Status}
+	for _, err := range errs {
+		r.Errors = append(r.Errors, err.Error())
+	}
+	return r
+}
+
+func TestParallelConstruction(t *testing.T) {
+	// Start counting again, a previous run left it at 3
+	atomic.StoreInt32(&started, 0)
+
+	route := newTestRoute(t, ParallelAPI{})
+	route.Options = &Options{Parallel: true}
+
+	res := serve(t, route, "GET", "/parallelapi/report")
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, res.Code, res.Body)
+	}
+
+	report := Report{}
+	err := json.Unmarshal(res.Body.Bytes(), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, status := range report.Parts {
+		if status != "together" {
+			t.Errorf("Part %d was constructed %s", i, status)
+		}
+	}
+
+	// Errors follow the plan order, not the order the Inits finished
+	if len(report.Errors) != 2 || report.Errors[0] != "PartA failed" || report.Errors[1] != "PartB failed" {
+		t.Errorf("Unexpected errors order %q", report.Errors)
+	}
+}
+
+type HaltAPI struct {
+	Halt Halt
+}
+
+type Halt struct{}
+type HaltFirst struct{}
+type HaltSecond struct{}
+
+// The client gives up while the first level is constructed
+var haltCancel func()
+var haltSecondRan int32
+
+func (f *HaltFirst) Init() {
+	haltCancel()
+}
+
+func (s *HaltSecond) Init(f HaltFirst) {
+	atomic.AddInt32(&haltSecondRan, 1)
+}
+
+func (h *Halt) GET(s HaltSecond) *Halt {
+	return h
+}
+
+func TestParallelCanceled(t *testing.T) {
+	atomic.StoreInt32(&haltSecondRan, 0)
+
+	logs := &bytes.Buffer{}
+	route := newTestRoute(t, HaltAPI{})
+	route.Options = &Options{Parallel: true, Logger: newTestLogger(logs)}
+
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	defer cancel()
+	haltCancel = cancel
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/haltapi/halt", nil)
+	route.ServeHTTP(res, req)
+
+	if n := atomic.LoadInt32(&haltSecondRan); n != 0 {
+		t.Errorf("Expected no Init call after the client gave up, got %d", n)
+	}
+
+	// Nobody is answered, the access log tells the client closed the request
+	if res.Body.Len() > 0 {
+		t.Errorf("Expected no response for a canceled request, got %s", res.Body)
+	}
+	if !strings.Contains(logs.String(), `"status":499`) {
+		t.Errorf("Expected the canceled request in the access log, got %s", logs)
+	}
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Encodes the responses in CSV
+// Each element of a Slice, iterator or channel is a row and the columns are its fields,
+// a single Struct is written as a single row
+// The fields of inner Structs are flattened in columns joined by dots, like author.name
+// The rows are written one by one, so big Slices are never held encoded in memory
+type CSVEncoder struct{}
+
+func (e CSVEncoder) MediaType() string {
+	return "text/csv"
+}
+
+func (e CSVEncoder) Encode(w io.Writer, v interface{}) error {
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return errCSVNotTabular
+	}
+
+	// The columns of Structs are known by its Type,
+	// other values have to be read all to know them
+	t := value.Type()
+	switch {
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		t = t.Elem()
+	case isSequence(t):
+		t = elemOfListType(t)
+	}
+	if elemOfType(t).Kind() != reflect.Struct || isEncodedByItself(t) {
+		return encodeGenericCSV(w, collectValue(v))
+	}
+
+	columns := csvColumns(elemOfType(t), "", nil, nil)
+
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.Name
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	writeRow := func(elem reflect.Value) error {
+		for i, c := range columns {
+			var err error
+			record[i], err = csvValueCell(c.value(elem))
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Write(record)
+	}
+
+	isList, err := eachElem(value, writeRow)
+	if err != nil {
+		return err
+	}
+	if !isList {
+		err = writeRow(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (e CSVEncoder) streams() {}
+
+func (e CSVEncoder) rows() {}
+
+var errCSVNotTabular = HTTPError{
+	Status:  http.StatusNotAcceptable,
+	Code:    "not_acceptable",
+	Message: "Only lists of objects can be answered in CSV",
+}
+
+// A column of the CSV, with the path of fields to its value
+type csvColumn struct {
+	Name   string
+	Fields []jsonField
+}
+
+// Return the Value of the column in the given element
+// It returns an invalid Value if some Struct in the path is nil
+func (c csvColumn) value(v reflect.Value) reflect.Value {
+	for _, f := range c.Fields {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = fieldValue(v, f)
+		if !v.IsValid() {
+			return v
+		}
+	}
+	return v
+}
+
+// Return the columns of the Struct in the order of its fields
+// The Structs already in the path are not flattened again, they are written in JSON
+func csvColumns(t reflect.Type, prefix string, path []jsonField, parents []reflect.Type) []csvColumn {
+
+	parents = append(parents, t)
+	columns := []csvColumn{}
+
+	for _, f := range jsonFields(t) {
+		fields := append(path[:len(path):len(path)], f)
+
+		inner := elemOfType(f.Type)
+		if inner.Kind() == reflect.Struct && !isEncodedByItself(f.Type) && !containsType(parents, inner) {
+			columns = append(columns, csvColumns(inner, prefix+f.Name+".", fields, parents)...)
+			continue
+		}
+
+		columns = append(columns, csvColumn{Name: prefix + f.Name, Fields: fields})
+	}
+
+	return columns
+}
+
+// Return true if the values of the Type are encoded by its own methods, like time.Time
+func isEncodedByItself(t reflect.Type) bool {
+	t = ptrOfType(elemOfType(t))
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+func containsType(types []reflect.Type, t reflect.Type) bool {
+	for _, each := range types {
+		if each == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the text of a field to be written in a cell
+// Lists, maps and the values with its own encoding are written as in JSON
+func csvValueCell(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", nil
+	}
+
+	if !isEncodedByItself(v.Type()) {
+		switch v.Kind() {
+		case reflect.String:
+			return v.String(), nil
+		case reflect.Bool:
+			return strconv.FormatBool(v.Bool()), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(v.Int(), 10), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return strconv.FormatUint(v.Uint(), 10), nil
+		}
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", errors.New("Error encoding a CSV cell: " + err.Error())
+	}
+
+	// Values encoded as JSON strings, like times, don't need the quotes
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		return text, nil
+	}
+	return string(data), nil
+}
+
+// Encode values whose columns are not known by its Type, like maps
+// The columns are all the keys found in the rows, sorted
+func encodeGenericCSV(w io.Writer, v interface{}) error {
+	generic, err := genericValue(v)
+	if err != nil {
+		return err
+	}
+
+	rows := []map[string]interface{}{}
+
+	switch value := generic.(type) {
+	case []interface{}:
+		for _, elem := range value {
+			row, ok := elem.(map[string]interface{})
+			if !ok {
+				return errCSVNotTabular
+			}
+			rows = append(rows, row)
+		}
+	case map[string]interface{}:
+		rows = append(rows, value)
+	default:
+		return errCSVNotTabular
+	}
+
+	// The columns are all the fields found in the rows
+	columns := []string{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for k := range row {
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	writer := csv.NewWriter(w)
+
+	err = writer.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i], err = csvCell(row[column])
+			if err != nil {
+				return err
+			}
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Return the text of a generic value to be written in a cell
+// Objects and lists are written in JSON
+func csvCell(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		if value {
+			return "true", nil
+		}
+		return "false", nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.New("Error encoding a CSV cell: " + err.Error())
+	}
+	return string(data), nil
+}
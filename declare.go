@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Implemented by the Resources that declare its handlers one by one
+// When a Resource has the Routes method, only the methods declared in it are handlers,
+// whatever their names are
+// Ex:
+//
+//	func (u *UserList) Routes() []api.Declaration {
+//		return []api.Declaration{
+//			{HTTPMethod: "GET", Method: "List", Doc: api.Doc{Summary: "List the users"}},
+//			{HTTPMethod: "POST", Action: "login", Method: "Login", Doc: api.Doc{Tags: []string{"auth"}}},
+//		}
+//	}
+type Declarer interface {
+	Routes() []Declaration
+}
+
+// A handler declared by a Resource
+type Declaration struct {
+	HTTPMethod string // GET, PUT, POST, DELETE or HEAD
+	Action     string // The path segment of an action, empty for the main handler of the Resource
+	Method     string // The name of the method that answers the requests
+	Doc        Doc
+}
+
+// When it is true, only the methods named exactly as the HTTP method, like GET,
+// or separated from the action by an underscore, like GET_Login, are handlers
+// So methods like GETTER or POSTCode are not mapped by accident
+var StrictMethodNames = false
+
+var declarerType = reflect.TypeOf((*Declarer)(nil)).Elem()
+
+// A method of a Resource that answers requests
+type mappedMethod struct {
+	Method     reflect.Method
+	HTTPMethod string
+	Name       string // The action name, empty for the main handler
+	Doc        Doc
+}
+
+// Return the methods of the Resource that are handlers
+// They are the methods declared in its Routes method, or the methods named by an HTTP method
+func mappedMethods(r *Resource) ([]mappedMethod, error) {
+
+	t := r.Value.Type()
+
+	if !t.Implements(declarerType) {
+		methods := []mappedMethod{}
+		for i := 0; i < t.NumMethod(); i++ {
+			m := t.Method(i)
+			if isMappedMethod(m) {
+				httpMethod, name := decodeMethodName(m)
+				methods = append(methods, mappedMethod{Method: m, HTTPMethod: httpMethod, Name: name})
+			}
+		}
+		return methods, nil
+	}
+
+	declarations := r.Value.Interface().(Declarer).Routes()
+	methods := make([]mappedMethod, len(declarations))
+
+	for i, d := range declarations {
+		if !isHTTPMethod(d.HTTPMethod) {
+			return nil, fmt.Errorf("The Routes of %s declare the method %s with an invalid HTTP method '%s'",
+				t, d.Method, d.HTTPMethod)
+		}
+		if strings.Contains(d.Action, "/") {
+			return nil, fmt.Errorf("The Routes of %s declare the action '%s' with a slash", t, d.Action)
+		}
+		m, exist := t.MethodByName(d.Method)
+		if !exist {
+			return nil, fmt.Errorf("The Routes of %s declare the method %s, but %s has no such method",
+				t, d.Method, t)
+		}
+		methods[i] = mappedMethod{Method: m, HTTPMethod: d.HTTPMethod, Name: d.Action, Doc: d.Doc}
+	}
+
+	return methods, nil
+}
+
+func isHTTPMethod(name string) bool {
+	for _, httpMethod := range httpMethods {
+		if name == httpMethod {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+type ClubAPI struct {
+	Players PlayerList
+}
+
+type PlayerList []Player
+
+func (l *PlayerList) Routes() []Declaration {
+	return []Declaration{
+		{HTTPMethod: "GET", Method: "List"},
+		{HTTPMethod: "POST", Action: "sign-up", Method: "SignUp", Doc: Doc{
+			Summary: "Sign up a player",
+			Tags:    []string{"players"},
+		}},
+	}
+}
+
+func (l *PlayerList) List() {}
+
+func (l *PlayerList) SignUp() {}
+
+// Not declared, so it is not a handler
+func (l *PlayerList) POSTCode() {}
+
+type Player struct{}
+
+func (p *Player) GET() {}
+
+func TestDeclaredRoutes(t *testing.T) {
+	route := newTestRoute(t, ClubAPI{})
+
+	tests := []struct {
+		Method string
+		URI    string
+		Status int
+	}{
+		{"GET", "/clubapi/players", http.StatusNoContent},
+		{"POST", "/clubapi/players/sign-up", http.StatusNoContent},
+		{"POST", "/clubapi/players/code", http.StatusNotFound},
+		{"GET", "/clubapi/players/3", http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		res := serve(t, route, test.Method, test.URI)
+		if res.Code != test.Status {
+			t.Errorf("%s %s: expected the status %d, got %d", test.Method, test.URI, test.Status, res.Code)
+		}
+	}
+
+	h := route.Children["players"].Handlers["POSTsign-up"]
+	if h == nil || h.Doc.Summary != "Sign up a player" || len(h.Doc.Tags) != 1 {
+		t.Errorf("Expected the Doc of the declared handler, got %v", h)
+	}
+}
+
+type UnknownMethodAPI struct{}
+
+func (u *UnknownMethodAPI) Routes() []Declaration {
+	return []Declaration{{HTTPMethod: "GET", Method: "Missing"}}
+}
+
+type InvalidMethodAPI struct{}
+
+func (i *InvalidMethodAPI) Routes() []Declaration {
+	return []Declaration{{HTTPMethod: "FETCH", Method: "Fetch"}}
+}
+
+func (i *InvalidMethodAPI) Fetch() {}
+
+func TestInvalidDeclarations(t *testing.T) {
+	tests := []struct {
+		Object   interface{}
+		Expected string
+	}{
+		{UnknownMethodAPI{}, "The Routes of *api.UnknownMethodAPI declare the method Missing, but *api.UnknownMethodAPI has no such method"},
+		{InvalidMethodAPI{}, "The Routes of *api.InvalidMethodAPI declare the method Fetch with an invalid HTTP method 'FETCH'"},
+	}
+
+	for _, test := range tests {
+		resource, err := NewResource(test.Object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewRoute(resource)
+		if err == nil || err.Error() != test.Expected {
+			t.Errorf("Expected the error %q, got %v", test.Expected, err)
+		}
+	}
+}
+
+type StrictAPI struct{}
+
+func (s *StrictAPI) GET() {}
+
+func (s *StrictAPI) GET_Login() {}
+
+func (s *StrictAPI) GETTER() {}
+
+func (s *StrictAPI) POSTCode() {}
+
+func TestStrictMethodNames(t *testing.T) {
+	tests := []struct {
+		Strict bool
+		Method string
+		URI    string
+		Status int
+	}{
+		{false, "GET", "/strictapi/ter", http.StatusNoContent},
+		{false, "POST", "/strictapi/code", http.StatusNoContent},
+		{true, "GET", "/strictapi", http.StatusNoContent},
+		{true, "GET", "/strictapi/login", http.StatusNoContent},
+		{true, "GET", "/strictapi/ter", http.StatusNotFound},
+		{true, "POST", "/strictapi/code", http.StatusNotFound},
+	}
+
+	defer func(strict bool) { StrictMethodNames = strict }(StrictMethodNames)
+
+	for _, test := range tests {
+		StrictMethodNames = test.Strict
+		route := newTestRoute(t, StrictAPI{})
+
+		res := serve(t, route, test.Method, test.URI)
+		if res.Code != test.Status {
+			t.Errorf("%s %s strict %v: expected the status %d, got %d", test.Method, test.URI, test.Strict, test.Status, res.Code)
+		}
+	}
+}
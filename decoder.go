@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Decodes the request body in the resource of the handler
+// It is chosen by the Content-Type header of the request
+type Decoder interface {
+	// The media type it reads, like application/json
+	MediaType() string
+
+	// Read the request body into the given Ptr
+	Decode(req *http.Request, v interface{}) error
+}
+
+// The registered Decoders, the first one is used
+// when the request doesn't say the type of its body
+var decoders = struct {
+	sync.RWMutex
+	list []Decoder
+}{
+	list: []Decoder{
+		JSONDecoder{},
+		XMLDecoder{},
+		FormDecoder{},
+		MultipartDecoder{},
+		MsgPackDecoder{},
+		CBORDecoder{},
+		JSONAPIDecoder{},
+	},
+}
+
+// Register a Decoder to read the bodies of its media type
+// It replaces the Decoder already registered for the same media type
+// Ex: api.RegisterDecoder(ProtobufDecoder{}) from some other package
+func RegisterDecoder(d Decoder) {
+	decoders.Lock()
+	defer decoders.Unlock()
+
+	for i, registered := range decoders.list {
+		if registered.MediaType() == d.MediaType() {
+			decoders.list[i] = d
+			return
+		}
+	}
+	decoders.list = append(decoders.list, d)
+}
+
+// Return the Decoder for the Content-Type header
+// Return an error with status 415 if no Decoder reads it
+func decoderFor(contentType string) (Decoder, error) {
+	decoders.RLock()
+	defer decoders.RUnlock()
+
+	if len(strings.TrimSpace(contentType)) == 0 {
+		return decoders.list[0], nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		for _, d := range decoders.list {
+			if d.MediaType() == mediaType {
+				return d, nil
+			}
+		}
+	}
+
+	return nil, HTTPError{
+		Status:  http.StatusUnsupportedMediaType,
+		Code:    "unsupported_media_type",
+		Message: "The body type '" + contentType + "' is not supported",
+	}
+}
+
+// Return true if the request has some body to be read
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0
+}
+
+// Decode the request body in the given Ptr with the Decoder
+// Errors that doesn't have a status are answered as 400
+func decodeBody(d Decoder, req *http.Request, v interface{}) error {
+
+	err := d.Decode(req, v)
+	if err != nil {
+		var se statusError
+		if errors.As(err, &se) {
+			return err
+		}
+		return HTTPError{
+			Status:  http.StatusBadRequest,
+			Code:    "bad_request",
+			Message: "Error decoding the body: " + err.Error(),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type MemberAPI struct {
+	Member Member
+}
+
+type Member struct {
+	Name    string   `json:"name"`
+	Age     int      `json:"age"`
+	Tags    []string `json:"tags"`
+	Address *Address `json:"address"`
+	Files   []string `json:"files"`
+}
+
+type Address struct {
+	City string `json:"city"`
+}
+
+func (m *Member) PUT() *Member {
+	return m
+}
+
+func (m *Member) POST(files []File) (*Member, error) {
+	for _, f := range files {
+		content, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, f.Field+":"+f.Filename+":"+string(data))
+	}
+	return m, nil
+}
+
+func serveBody(t *testing.T, ro *Route, method, uri, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	ro.ServeHTTP(res, req)
+	return res
+}
+
+func TestDecodeBody(t *testing.T) {
+	route := newTestRoute(t, MemberAPI{})
+
+	tests := []struct {
+		ContentType string
+		Body        string
+	}{
+		{"application/json", `{"name": "Ana", "age": 30, "tags": ["a", "b"], "address": {"city": "Recife"}}`},
+		{"application/json; charset=utf-8", `{"name": "Ana", "age": 30, "tags": ["a", "b"], "address": {"city": "Recife"}}`},
+		{"application/xml", `<Member><Name>Ana</Name><Age>30</Age><Tags>a</Tags><Tags>b</Tags><Address><City>Recife</City></Address></Member>`},
+		{"application/x-www-form-urlencoded", `name=Ana&age=30&tags=a&tags=b&address.city=Recife`},
+	}
+
+	for _, test := range tests {
+		res := serveBody(t, route, "PUT", "/memberapi/member", test.ContentType, strings.NewReader(test.Body))
+		if res.Code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", test.ContentType, http.StatusOK, res.Code, res.Body)
+			continue
+		}
+
+		m := Member{}
+		err := json.Unmarshal(res.Body.Bytes(), &m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Name != "Ana" || m.Age != 30 || len(m.Tags) != 2 || m.Address == nil || m.Address.City != "Recife" {
+			t.Errorf("%s: unexpected member decoded %+v", test.ContentType, m)
+		}
+	}
+
+	res := serveBody(t, route, "PUT", "/memberapi/member", "text/plain", strings.NewReader("Ana"))
+	if res.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusUnsupportedMediaType, res.Code, res.Body)
+	}
+
+	res = serveBody(t, route, "PUT", "/memberapi/member", "application/json", strings.NewReader("{name"))
+	if res.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, res.Code, res.Body)
+	}
+}
+
+func TestDecodeMultipart(t *testing.T) {
+	route := newTestRoute(t, MemberAPI{})
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "Ana")
+	part, _ := writer.CreateFormFile("photo", "ana.png")
+	part.Write([]byte("PNG"))
+	part, _ = writer.CreateFormFile("cv", "ana.txt")
+	part.Write([]byte("TXT"))
+	writer.Close()
+
+	res := serveBody(t, route, "POST", "/memberapi/member", writer.FormDataContentType(), body)
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, res.Code, res.Body)
+	}
+
+	response := map[string]Member{}
+	err := json.Unmarshal(res.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := response["member"]
+	if m.Name != "Ana" {
+		t.Errorf("Expected name Ana, got %q", m.Name)
+	}
+	if len(m.Files) != 2 || m.Files[0] != "cv:ana.txt:TXT" || m.Files[1] != "photo:ana.png:PNG" {
+		t.Errorf("Unexpected files %q", m.Files)
+	}
+}
+
+type GuardAPI struct {
+	Guard  Guard
+	Upload Upload
+}
+
+// Counts its Init calls, the body is decoded after them
+type Guard struct {
+	Name string `json:"name"`
+	City string `json:"city"`
+}
+
+var guardInits int32
+
+func (g *Guard) Init() {
+	atomic.AddInt32(&guardInits, 1)
+	g.Name = "Init"
+	g.City = "Init"
+}
+
+func (g *Guard) PUT() *Guard {
+	return g
+}
+
+// Reads the request body itself
+type Upload struct {
+	Size int
+}
+
+func (u *Upload) PUT(req *http.Request) (*Upload, error) {
+	data, err := io.ReadAll(req.Body)
+	u.Size = len(data)
+	return u, err
+}
+
+// Wraps the status error of its body
+type wrappingDecoder struct{}
+
+func (wrappingDecoder) MediaType() string { return "application/x-wrapping" }
+
+func (wrappingDecoder) Decode(req *http.Request, v interface{}) error {
+	return fmt.Errorf("Reading %s: %w", req.URL.Path, HTTPError{Status: http.StatusUnprocessableEntity})
+}
+
+func TestDecodeAfterInit(t *testing.T) {
+	atomic.StoreInt32(&guardInits, 0)
+	route := newTestRoute(t, GuardAPI{})
+
+	// The Decoder is chosen before the Inits
+	res := serveBody(t, route, "PUT", "/guardapi/guard", "text/plain", strings.NewReader("Ana"))
+	if res.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusUnsupportedMediaType, res.Code, res.Body)
+	}
+	if n := atomic.LoadInt32(&guardInits); n != 0 {
+		t.Errorf("Expected no Init call for an unsupported body, got %d", n)
+	}
+
+	// The body updates the fields it sends, the others keep the Init values
+	res = serveBody(t, route, "PUT", "/guardapi/guard", "application/json", strings.NewReader(`{"name": "Ana"}`))
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, res.Code, res.Body)
+	}
+	g := Guard{}
+	err := json.Unmarshal(res.Body.Bytes(), &g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Name != "Ana" || g.City != "Init" {
+		t.Errorf("Unexpected guard %+v", g)
+	}
+
+	// A body no Decoder reads is left to the handler that reads the request
+	res = serveBody(t, route, "PUT", "/guardapi/upload", "application/octet-stream", strings.NewReader("PNG"))
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"Size": 3`) {
+		t.Errorf("Unexpected upload response %d: %s", res.Code, res.Body)
+	}
+}
+
+func TestDecodeWrappedStatus(t *testing.T) {
+	RegisterDecoder(wrappingDecoder{})
+	route := newTestRoute(t, GuardAPI{})
+
+	res := serveBody(t, route, "PUT", "/guardapi/guard", "application/x-wrapping", strings.NewReader("Ana"))
+	if res.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusUnprocessableEntity, res.Code, res.Body)
+	}
+}
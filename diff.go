@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// If a change can break the clients of the API
+type ChangeKind int
+
+const (
+	// The clients keep working, like with a new endpoint or a new optional field
+	Additive ChangeKind = iota
+	// The clients can stop working, like with a removed endpoint or field
+	Breaking
+)
+
+func (k ChangeKind) String() string {
+	if k == Breaking {
+		return "breaking"
+	}
+	return "additive"
+}
+
+// A change of an endpoint between two versions of the API
+type Change struct {
+	Kind     ChangeKind
+	Endpoint string // The HTTP method and path template, like GET /api/a/bs/{id}
+	Message  string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s %s", c.Kind, c.Endpoint, c.Message)
+}
+
+// The changes between two versions of the API, sorted by path and HTTP method
+type DiffReport struct {
+	Changes []Change
+}
+
+// Return the changes that can break the clients
+func (r DiffReport) Breaking() []Change {
+	breaking := []Change{}
+	for _, c := range r.Changes {
+		if c.Kind == Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+	return breaking
+}
+
+// Return true if some change can break the clients,
+// so the release should be looked at before it goes out
+func (r DiffReport) HasBreaking() bool {
+	return len(r.Breaking()) > 0
+}
+
+// One change per line
+func (r DiffReport) String() string {
+	lines := make([]string, len(r.Changes))
+	for i, c := range r.Changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Return the changes from the Route tree to the other one
+func Diff(from, to *Route) DiffReport {
+	return diffDumps(from.dump(), to.dump())
+}
+
+// Return the changes between two dumps written by DumpRoutes in JSON
+func DiffSnapshots(from, to io.Reader) (DiffReport, error) {
+	dumps := make([]routeDump, 2)
+	for i, r := range []io.Reader{from, to} {
+		err := json.NewDecoder(r).Decode(&dumps[i])
+		if err != nil {
+			return DiffReport{}, fmt.Errorf("Error reading the Route dump: %s", err)
+		}
+	}
+	return diffDumps(dumps[0], dumps[1]), nil
+}
+
+func diffDumps(from, to routeDump) DiffReport {
+
+	before := from.handlersByEndpoint(map[string]handlerDump{})
+	after := to.handlersByEndpoint(map[string]handlerDump{})
+
+	report := DiffReport{Changes: []Change{}}
+	add := func(kind ChangeKind, endpoint, format string, args ...interface{}) {
+		report.Changes = append(report.Changes, Change{Kind: kind, Endpoint: endpoint, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for endpoint, o := range before {
+		n, exist := after[endpoint]
+		if !exist {
+			add(Breaking, endpoint, "was removed")
+			continue
+		}
+
+		if strings.Join(o.Outputs, ", ") != strings.Join(n.Outputs, ", ") {
+			add(Breaking, endpoint, "changed its outputs from (%s) to (%s)",
+				strings.Join(o.Outputs, ", "), strings.Join(n.Outputs, ", "))
+		}
+
+		// The clients read the response, so they miss the removed fields
+		oldResponse, newResponse := fieldsByName(o.Response), fieldsByName(n.Response)
+		for name, f := range oldResponse {
+			nf, exist := newResponse[name]
+			switch {
+			case !exist:
+				add(Breaking, endpoint, "removed the response field %s", name)
+			case nf.Type != f.Type:
+				add(Breaking, endpoint, "changed the type of the response field %s from %s to %s", name, f.Type, nf.Type)
+			}
+		}
+		for name := range newResponse {
+			if _, exist := oldResponse[name]; !exist {
+				add(Additive, endpoint, "added the response field %s", name)
+			}
+		}
+
+		// The clients write the request, so they don't send the new required fields
+		oldRequest, newRequest := fieldsByName(o.Request), fieldsByName(n.Request)
+		for name, f := range newRequest {
+			of, exist := oldRequest[name]
+			switch {
+			case !exist && f.Optional:
+				add(Additive, endpoint, "added the optional request field %s", name)
+			case !exist:
+				add(Breaking, endpoint, "added the required request field %s", name)
+			case of.Type != f.Type:
+				add(Breaking, endpoint, "changed the type of the request field %s from %s to %s", name, of.Type, f.Type)
+			case of.Optional && !f.Optional:
+				add(Breaking, endpoint, "made the request field %s required", name)
+			}
+		}
+
+		// The new inputs are required from the request, like an ID or a dependency
+		oldInputs, newInputs := clientInputs(o.Inputs), clientInputs(n.Inputs)
+		for input := range newInputs {
+			if !oldInputs[input] {
+				add(Breaking, endpoint, "requires the input %s", input)
+			}
+		}
+		for input := range oldInputs {
+			if !newInputs[input] {
+				add(Additive, endpoint, "no longer requires the input %s", input)
+			}
+		}
+
+		if n.Files && !o.Files {
+			add(Breaking, endpoint, "requires files in the request")
+		}
+	}
+
+	for endpoint := range after {
+		if _, exist := before[endpoint]; !exist {
+			add(Additive, endpoint, "was added")
+		}
+	}
+
+	// Sorted like the Walk of the endpoints, by path and HTTP method
+	sort.Slice(report.Changes, func(i, j int) bool {
+		a, b := report.Changes[i], report.Changes[j]
+		aMethod, aPath, _ := strings.Cut(a.Endpoint, " ")
+		bMethod, bPath, _ := strings.Cut(b.Endpoint, " ")
+		switch {
+		case aPath != bPath:
+			return aPath < bPath
+		case aMethod != bMethod:
+			return aMethod < bMethod
+		}
+		return a.Message < b.Message
+	})
+
+	return report
+}
+
+// Add the handlers of the dumped Route and its children by its endpoint, like GET /api/a/bs/{id}
+func (d routeDump) handlersByEndpoint(handlers map[string]handlerDump) map[string]handlerDump {
+	for _, h := range d.Handlers {
+		path := d.Path
+		if len(h.Action) > 0 {
+			path += "/" + h.Action
+		}
+		handlers[h.HTTPMethod+" "+path] = h
+	}
+	if d.Elem != nil {
+		d.Elem.handlersByEndpoint(handlers)
+	}
+	for _, child := range d.Children {
+		child.handlersByEndpoint(handlers)
+	}
+	return handlers
+}
+
+func fieldsByName(fields []fieldDump) map[string]fieldDump {
+	byName := map[string]fieldDump{}
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	return byName
+}
+
+// The inputs given by the framework, without the client sending anything
+// The Files are compared by the files of the handler
+var contextInputs = map[string]bool{
+	"error":               true,
+	"[]error":             true,
+	"http.ResponseWriter": true,
+	"*http.Request":       true,
+	"*api.Meta":           true,
+	"*slog.Logger":        true,
+	"api.File":            true,
+	"[]api.File":          true,
+}
+
+// Return the inputs of the handler that depend on the request
+func clientInputs(inputs []string) map[string]bool {
+	set := map[string]bool{}
+	for _, input := range inputs {
+		if !contextInputs[input] {
+			set[input] = true
+		}
+	}
+	return set
+}
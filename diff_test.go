@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	before := `{"name":"shop","type":"*api.Shop","path":"/shop","children":[
+		{"name":"orders","type":"*api.OrderList","path":"/shop/orders","handlers":[
+			{"method":"GET","func":"","outputs":["*api.OrderList"],"response":[
+				{"name":"id","type":"int"},{"name":"total","type":"int"},{"name":"note","type":"string","optional":true}]},
+			{"method":"POST","func":"","outputs":[],"request":[
+				{"name":"id","type":"int"},{"name":"total","type":"int"},{"name":"note","type":"string","optional":true}]},
+			{"method":"GET","action":"receipt","func":"","outputs":["string"]}
+		],"elem":
+			{"name":"orders","type":"*api.Order","path":"/shop/orders/{id}","handlers":[
+				{"method":"PUT","func":"","inputs":["*api.ID","*api.Shop","error"],"outputs":[]}]}
+		}]}`
+
+	after := `{"name":"shop","type":"*api.Shop","path":"/shop","children":[
+		{"name":"orders","type":"*api.OrderList","path":"/shop/orders","handlers":[
+			{"method":"GET","func":"","outputs":["*api.OrderList"],"response":[
+				{"name":"id","type":"int"},{"name":"total","type":"float64"},{"name":"currency","type":"string"}]},
+			{"method":"POST","func":"","outputs":["*api.Order"],"request":[
+				{"name":"id","type":"int"},{"name":"total","type":"int"},{"name":"note","type":"string"},
+				{"name":"currency","type":"string"},{"name":"coupon","type":"string","optional":true}]}
+		],"elem":
+			{"name":"orders","type":"*api.Order","path":"/shop/orders/{id}","handlers":[
+				{"method":"PUT","func":"","inputs":["*api.ID","*api.Customer","[]api.File"],"outputs":[],"files":true},
+				{"method":"DELETE","func":"","outputs":[]}]}
+		}]}`
+
+	report, err := DiffSnapshots(strings.NewReader(before), strings.NewReader(after))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `additive: GET /shop/orders added the response field currency
+breaking: GET /shop/orders changed the type of the response field total from int to float64
+breaking: GET /shop/orders removed the response field note
+additive: POST /shop/orders added the optional request field coupon
+breaking: POST /shop/orders added the required request field currency
+breaking: POST /shop/orders changed its outputs from () to (*api.Order)
+breaking: POST /shop/orders made the request field note required
+breaking: GET /shop/orders/receipt was removed
+additive: DELETE /shop/orders/{id} was added
+additive: PUT /shop/orders/{id} no longer requires the input *api.Shop
+breaking: PUT /shop/orders/{id} requires files in the request
+breaking: PUT /shop/orders/{id} requires the input *api.Customer`
+
+	if report.String() != expected {
+		t.Errorf("Expected the changes\n%s\ngot\n%s", expected, report)
+	}
+	if !report.HasBreaking() || len(report.Breaking()) != 8 {
+		t.Errorf("Expected 8 breaking changes, got %d", len(report.Breaking()))
+	}
+
+	_, err = DiffSnapshots(strings.NewReader("{"), strings.NewReader(after))
+	if err == nil {
+		t.Error("Expected an error for an invalid dump")
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	route := newTestRoute(t, API{})
+
+	// A dump written before is the same as the Route
+	dump := &bytes.Buffer{}
+	err := DumpRoutes(dump, route, DumpJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := &bytes.Buffer{}
+	DumpRoutes(current, newTestRoute(t, API{}), DumpJSON)
+
+	report, err := DiffSnapshots(dump, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 0 {
+		t.Errorf("Expected no changes, got\n%s", report)
+	}
+
+	// The A Resource as the root, so its endpoints moved to /api
+	resource, err := NewResource(A{}, "API")
+	if err != nil {
+		t.Fatal(err)
+	}
+	smaller, err := NewRoute(resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report = Diff(route, smaller)
+	for _, change := range []string{"breaking: GET /api/a was removed", "additive: GET /api was added"} {
+		if !strings.Contains(report.String()+"\n", change+"\n") {
+			t.Errorf("Expected the change %q, got\n%s", change, report)
+		}
+	}
+}
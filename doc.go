@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// What a handler is for, used by the documentation of the API
+type Doc struct {
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Examples of the request and response bodies
+	Request  interface{}
+	Response interface{}
+
+	// A deprecated handler answers with the Deprecation header,
+	// with the date it was deprecated when it is known
+	Deprecated  bool
+	Deprecation time.Time
+
+	// When the handler will stop answering, sent in the Sunset header
+	Sunset time.Time
+}
+
+// The Doc of each handler by the name of its method, like GET or GETLogin
+type Docs map[string]Doc
+
+// Implemented by the Resources that describe its handlers
+// Ex:
+//
+//	func (u *UserList) Describe() api.Docs {
+//		return api.Docs{
+//			"GET":      {Summary: "List the users", Tags: []string{"users"}},
+//			"GETLogin": {Summary: "Old login", Deprecated: true},
+//		}
+//	}
+type Describer interface {
+	Describe() Docs
+}
+
+var describerType = reflect.TypeOf((*Describer)(nil)).Elem()
+
+// The format of the dates in the api tags, like api:"sunset=2027-01-31"
+const docDateLayout = "2006-01-02"
+
+// Return true if the handler is deprecated, or has a deprecation date
+func (d Doc) IsDeprecated() bool {
+	return d.Deprecated || !d.Deprecation.IsZero()
+}
+
+// Return the Doc with the fields of the other Doc that are set
+func (d Doc) merge(other Doc) Doc {
+	if len(other.Summary) > 0 {
+		d.Summary = other.Summary
+	}
+	if len(other.Description) > 0 {
+		d.Description = other.Description
+	}
+	if len(other.Tags) > 0 {
+		d.Tags = other.Tags
+	}
+	if other.Request != nil {
+		d.Request = other.Request
+	}
+	if other.Response != nil {
+		d.Response = other.Response
+	}
+	if other.Deprecated {
+		d.Deprecated = true
+	}
+	if !other.Deprecation.IsZero() {
+		d.Deprecation = other.Deprecation
+	}
+	if !other.Sunset.IsZero() {
+		d.Sunset = other.Sunset
+	}
+	return d
+}
+
+// Return the Doc of all the handlers of the Resource, from the tag of its field
+// Ex: api:"summary=The orders,tag=billing,deprecated=2026-01-31,sunset=2027-01-31"
+func tagDoc(r *Resource) (Doc, error) {
+	doc := Doc{}
+	tag := r.Tag
+
+	doc.Summary, _ = apiOption(tag, "summary")
+	doc.Tags = apiOptions(tag, "tag")
+	doc.Deprecated = hasAPIOption(tag, "deprecated")
+
+	dates := []struct {
+		Key  string
+		Date *time.Time
+	}{
+		{"deprecated", &doc.Deprecation},
+		{"sunset", &doc.Sunset},
+	}
+	for _, d := range dates {
+		value, ok := apiOption(tag, d.Key)
+		if !ok {
+			continue
+		}
+		date, err := time.Parse(docDateLayout, value)
+		if err != nil {
+			return doc, fmt.Errorf("The %s date '%s' of the resource %s is not like %s",
+				d.Key, value, r.Name, docDateLayout)
+		}
+		*d.Date = date
+	}
+
+	return doc, nil
+}
+
+// Return the Docs of the handlers of the Resource, from its Describe method
+func describedDocs(r *Resource) Docs {
+	if !r.Value.Type().Implements(describerType) {
+		return nil
+	}
+	return r.Value.Interface().(Describer).Describe()
+}
+
+// Return the Docs of the handlers of this Route, by the name of its methods
+func (ro *Route) Docs() Docs {
+	docs := Docs{}
+	for _, h := range ro.Handlers {
+		docs[h.Method.Method.Name] = h.Doc
+	}
+	return docs
+}
+
+// Send the Deprecation and Sunset headers of a deprecated handler
+// The Deprecation is the date as @seconds, or true when it is not known
+func setDeprecationHeaders(w http.ResponseWriter, doc Doc) {
+	if !doc.Deprecation.IsZero() {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(doc.Deprecation.Unix(), 10))
+	} else if doc.Deprecated {
+		w.Header().Set("Deprecation", "true")
+	}
+	if !doc.Sunset.IsZero() {
+		w.Header().Set("Sunset", doc.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
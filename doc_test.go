@@ -0,0 +1,106 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ArchiveAPI struct {
+	Records RecordList `api:"tag=archive,tag=legacy,deprecated=2026-01-31,sunset=2027-01-31"`
+	Notes   NoteList   `api:"summary=The notes"`
+}
+
+type RecordList []Record
+
+func (l *RecordList) GET() {}
+
+type Record struct{}
+
+type NoteList []Note
+
+func (l *NoteList) Describe() Docs {
+	return Docs{
+		"GET": {
+			Summary:  "List the notes",
+			Tags:     []string{"notes"},
+			Response: []Note{{Text: "Buy milk"}},
+		},
+		"GETOld": {Deprecated: true},
+	}
+}
+
+func (l *NoteList) GET() {}
+
+func (l *NoteList) GETOld() {}
+
+func (l *NoteList) GETPinned() {}
+
+type Note struct {
+	Text string
+}
+
+func TestDocs(t *testing.T) {
+	route := newTestRoute(t, ArchiveAPI{})
+
+	deprecation, _ := time.Parse(docDateLayout, "2026-01-31")
+	sunset, _ := time.Parse(docDateLayout, "2027-01-31")
+
+	tests := []struct {
+		Route    *Route
+		Method   string
+		Expected Doc
+	}{
+		{route.Children["records"], "GET", Doc{Tags: []string{"archive", "legacy"}, Deprecation: deprecation, Sunset: sunset}},
+		{route.Children["notes"], "GET", Doc{Summary: "List the notes", Tags: []string{"notes"}, Response: []Note{{Text: "Buy milk"}}}},
+		{route.Children["notes"], "GETOld", Doc{Summary: "The notes", Deprecated: true}},
+		{route.Children["notes"], "GETPinned", Doc{Summary: "The notes"}},
+	}
+
+	for _, test := range tests {
+		doc := test.Route.Docs()[test.Method]
+		if !reflect.DeepEqual(doc, test.Expected) {
+			t.Errorf("%s %s: expected the Doc\n%#v\ngot\n%#v", test.Route.Name, test.Method, test.Expected, doc)
+		}
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	route := newTestRoute(t, ArchiveAPI{})
+
+	tests := []struct {
+		URI         string
+		Deprecation string
+		Sunset      string
+	}{
+		{"/archiveapi/records", "@1769817600", "Sun, 31 Jan 2027 00:00:00 GMT"},
+		{"/archiveapi/notes/old", "true", ""},
+		{"/archiveapi/notes", "", ""},
+	}
+
+	for _, test := range tests {
+		res := serve(t, route, "GET", test.URI)
+		if res.Header().Get("Deprecation") != test.Deprecation {
+			t.Errorf("%s: expected the Deprecation %q, got %q", test.URI, test.Deprecation, res.Header().Get("Deprecation"))
+		}
+		if res.Header().Get("Sunset") != test.Sunset {
+			t.Errorf("%s: expected the Sunset %q, got %q", test.URI, test.Sunset, res.Header().Get("Sunset"))
+		}
+	}
+}
+
+type BadDateAPI struct {
+	Records RecordList `api:"sunset=tomorrow"`
+}
+
+func TestInvalidDocDate(t *testing.T) {
+	resource, err := NewResource(BadDateAPI{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewRoute(resource)
+	expected := "The sunset date 'tomorrow' of the resource records is not like 2006-01-02"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected the error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The formats the Route tree can be dumped in
+type DumpFormat int
+
+const (
+	// The Route tree as indented JSON, like Route.MarshalJSON
+	DumpJSON DumpFormat = iota
+	// One line per endpoint followed by its dependencies, easy to read in a diff
+	DumpText
+)
+
+// The Route tree in a stable order, as it is dumped
+type routeDump struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Path     string        `json:"path"`
+	Handlers []handlerDump `json:"handlers,omitempty"`
+	Elem     *routeDump    `json:"elem,omitempty"`
+	Children []routeDump   `json:"children,omitempty"`
+}
+
+type handlerDump struct {
+	HTTPMethod   string           `json:"method"`
+	Action       string           `json:"action,omitempty"`
+	Func         string           `json:"func"`
+	Inputs       []string         `json:"inputs"`
+	Outputs      []string         `json:"outputs"`
+	Dependencies []dependencyDump `json:"dependencies"`
+
+	// The fields of the bodies, nested fields are joined by dots like author.name
+	Request  []fieldDump `json:"request,omitempty"`
+	Response []fieldDump `json:"response,omitempty"`
+
+	// True if the handler reads the Files sent in the request
+	Files bool `json:"files,omitempty"`
+}
+
+type fieldDump struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Optional bool   `json:"optional,omitempty"` // Tagged omitempty, or inside a field that is
+}
+
+type dependencyDump struct {
+	Type  string   `json:"type"`
+	Level int      `json:"level"`
+	Init  []string `json:"init,omitempty"` // The inputs of the Init method, without the dependency itself
+}
+
+// Write the whole Route tree as JSON, with the handlers and its dependencies
+// The children and handlers are sorted, so the same tree is always written the same way
+func (ro *Route) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ro.dump())
+}
+
+// Write the Route tree in the given format
+// It is meant to be checked in, to notice when an endpoint changes
+func DumpRoutes(w io.Writer, ro *Route, format DumpFormat) error {
+	switch format {
+	case DumpJSON:
+		data, err := json.MarshalIndent(ro.dump(), "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+
+	case DumpText:
+		return ro.Walk(func(e Endpoint) error {
+			_, err := fmt.Fprintf(w, "%s %s %s\n", e.HTTPMethod, e.Path, funcName(e))
+			if err != nil {
+				return err
+			}
+			for _, d := range e.Dependencies {
+				line := "\t" + d.Type.String()
+				if d.Init != nil {
+					line += " Init(" + strings.Join(typeNames(initInputs(d.Init)), ", ") + ")"
+				}
+				_, err = io.WriteString(w, line+"\n")
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return fmt.Errorf("Unknown dump format %d", format)
+}
+
+// Return the Route and its children in a stable order
+func (ro *Route) dump() routeDump {
+
+	path, _ := ro.path(idMap{})
+	d := routeDump{
+		Name: ro.Name,
+		Type: ro.Value.Type().String(),
+		Path: path,
+	}
+
+	keys := []string{}
+	for key := range ro.Handlers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		d.Handlers = append(d.Handlers, ro.Handlers[key].dump())
+	}
+
+	if ro.IsSlice && ro.Elem != nil {
+		elem := ro.Elem.dump()
+		d.Elem = &elem
+	}
+
+	names := []string{}
+	for name := range ro.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d.Children = append(d.Children, ro.Children[name].dump())
+	}
+
+	return d
+}
+
+func (h *handler) dump() handlerDump {
+	e := h.endpoint()
+
+	d := handlerDump{
+		HTTPMethod:   e.HTTPMethod,
+		Action:       e.Action,
+		Func:         funcName(e),
+		Inputs:       typeNames(e.Inputs),
+		Outputs:      typeNames(e.Outputs),
+		Dependencies: []dependencyDump{},
+	}
+
+	for _, dep := range e.Dependencies {
+		dd := dependencyDump{Type: dep.Type.String(), Level: dep.Level}
+		if dep.Init != nil {
+			dd.Init = typeNames(initInputs(dep.Init))
+		}
+		d.Dependencies = append(d.Dependencies, dd)
+	}
+
+	// Only PUT and POST are expected to have a body
+	if e.HTTPMethod == "PUT" || e.HTTPMethod == "POST" {
+		d.Request = bodyFields(h.Method.Owner, "", false)
+	}
+	d.Response = h.responseFields()
+	d.Files = h.Plan != nil && h.Plan.Files
+
+	return d
+}
+
+// Return the fields of the response body, as they are sent
+func (h *handler) responseFields() []fieldDump {
+	m := h.Method
+
+	// A single output is sent by itself
+	if m.Response == nil && m.NumOut == 1 {
+		return bodyFields(m.Outputs[0], "", false)
+	}
+
+	// The named outputs are the fields of the response
+	fields := []fieldDump{}
+	if m.Response != nil {
+		for _, f := range m.Response {
+			fields = append(fields, fieldDump{Name: f.Name, Type: f.Type.String(), Optional: f.OmitEmpty})
+			fields = append(fields, bodyFields(f.Type, f.Name+".", f.OmitEmpty)...)
+		}
+		return fields
+	}
+	for i, t := range m.Outputs {
+		fields = append(fields, fieldDump{Name: m.OutName[i], Type: t.String()})
+		fields = append(fields, bodyFields(t, m.OutName[i]+".", false)...)
+	}
+	return fields
+}
+
+// Return the fields of the Struct, or of the elements of the list, sent in a body
+func bodyFields(t reflect.Type, prefix string, optional bool) []fieldDump {
+	t = elemOfType(t)
+	switch {
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		t = t.Elem()
+	case isSequence(t):
+		t = elemOfListType(t)
+	}
+	t = elemOfType(t)
+	if t.Kind() != reflect.Struct || isEncodedByItself(t) {
+		return nil
+	}
+
+	fields := []fieldDump{}
+	for _, c := range csvColumns(t, prefix, nil, nil) {
+		f := fieldDump{Name: c.Name, Type: c.Fields[len(c.Fields)-1].Type.String(), Optional: optional}
+		for _, each := range c.Fields {
+			f.Optional = f.Optional || each.OmitEmpty
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// Return the method of the endpoint like (*api.Item).GET(*api.ID) *api.Answer
+func funcName(e Endpoint) string {
+	name := fmt.Sprintf("(%s).%s(%s)", e.Resource, e.Method, strings.Join(typeNames(e.Inputs), ", "))
+	switch len(e.Outputs) {
+	case 0:
+		return name
+	case 1:
+		return name + " " + e.Outputs[0].String()
+	}
+	return name + " (" + strings.Join(typeNames(e.Outputs), ", ") + ")"
+}
+
+// Return the inputs of the Init method, without the dependency itself
+func initInputs(t reflect.Type) []reflect.Type {
+	inputs := []reflect.Type{}
+	for i := 1; i < t.NumIn(); i++ {
+		inputs = append(inputs, t.In(i))
+	}
+	return inputs
+}
+
+func typeNames(types []reflect.Type) []string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return names
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestDumpText(t *testing.T) {
+	route := newTestRoute(t, API{})
+
+	expected := `GET /api/a (*api.A).GET(api.B, error) *api.A
+	*api.A Init()
+	*api.B Init(*api.ID)
+GET /api/a/bs (*api.BList).GET() *api.BList
+	*api.BList Init()
+GET /api/a/bs/login (*api.BList).GETLogin() api.BList
+	*api.BList Init()
+GET /api/a/bs/{id} (*api.B).GET(*api.ID) *api.B
+	*api.B Init(*api.ID)
+PUT /api/a/bs/{id} (*api.B).PUT()
+	*api.B Init(*api.ID)
+GET /api/a/bs/{id}/cs/{id} (*api.C).GET() *api.C
+	*api.B Init(*api.ID)
+	*api.A Init()
+	*api.C Init(*api.ID, api.B, api.A)
+PUT /api/a/bsx (*api.A).PUTBsx() *api.A
+	*api.A Init()
+`
+
+	dump := &bytes.Buffer{}
+	err := DumpRoutes(dump, route.Children["a"], DumpText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.String() != expected {
+		t.Errorf("Expected the dump\n%s\ngot\n%s", expected, dump)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	route := newTestRoute(t, ParallelAPI{})
+
+	expected := `{"name":"parallelapi","type":"*api.ParallelAPI","path":"/parallelapi","children":[` +
+		`{"name":"report","type":"*api.Report","path":"/parallelapi/report","handlers":[` +
+		`{"method":"GET","func":"(*api.Report).GET(api.PartA, api.PartB, api.PartC, []error) *api.Report",` +
+		`"inputs":["api.PartA","api.PartB","api.PartC","[]error"],"outputs":["*api.Report"],"dependencies":[` +
+		`{"type":"*api.Report","level":0},{"type":"*api.PartA","level":0},` +
+		`{"type":"*api.PartB","level":0},{"type":"*api.PartC","level":0}],` +
+		`"response":[{"name":"Parts","type":"[]string"},{"name":"Errors","type":"[]string"}]}]}]}`
+
+	data, err := json.Marshal(route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Errorf("Expected the JSON\n%s\ngot\n%s", expected, data)
+	}
+
+	// The maps of the tree never change the order of the dump
+	first := &bytes.Buffer{}
+	DumpRoutes(first, newTestRoute(t, RouterAPI{}), DumpJSON)
+	for i := 0; i < 10; i++ {
+		again := &bytes.Buffer{}
+		DumpRoutes(again, newTestRoute(t, RouterAPI{}), DumpJSON)
+		if again.String() != first.String() {
+			t.Fatalf("Expected the same dump every time, got\n%s\nand\n%s", first, again)
+		}
+	}
+}
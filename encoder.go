@@ -0,0 +1,422 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encodes the responses in some media type
+// It is chosen by the Accept header of the request
+type Encoder interface {
+	// The media type it writes, like application/json
+	MediaType() string
+
+	// Write the given value encoded in the media type
+	Encode(w io.Writer, v interface{}) error
+}
+
+// The registered Encoders, the first one is used
+// when the request doesn't say what it accepts
+var encoders = struct {
+	sync.RWMutex
+	list []Encoder
+}{
+	list: []Encoder{
+		JSONEncoder{Indent: "\t"},
+		XMLEncoder{Indent: "\t"},
+		YAMLEncoder{},
+		CSVEncoder{},
+		NDJSONEncoder{},
+		JSONAPIEncoder{Indent: "\t"},
+		HALEncoder{Indent: "\t"},
+		MsgPackEncoder{},
+		CBOREncoder{},
+	},
+}
+
+// Register an Encoder to answer the requests that accept its media type
+// It replaces the Encoder already registered for the same media type
+// Ex: api.RegisterEncoder(api.JSONEncoder{}) for compact JSON responses
+func RegisterEncoder(e Encoder) {
+	encoders.Lock()
+	defer encoders.Unlock()
+
+	for i, registered := range encoders.list {
+		if registered.MediaType() == e.MediaType() {
+			encoders.list[i] = e
+			return
+		}
+	}
+	encoders.list = append(encoders.list, e)
+}
+
+// A media range of the Accept header, like text/* or application/json;q=0.8
+type mediaRange struct {
+	Type    string
+	SubType string
+	Q       float64
+}
+
+// Return the Encoder that best matches the Accept header
+// Return an error with status 406 if no Encoder is acceptable
+func negotiate(accept string) (Encoder, error) {
+	encoders.RLock()
+	defer encoders.RUnlock()
+
+	if len(strings.TrimSpace(accept)) == 0 {
+		return encoders.list[0], nil
+	}
+
+	ranges := parseAccept(accept)
+
+	var best Encoder
+	bestQ := 0.0
+
+	for _, e := range encoders.list {
+		q := acceptQuality(e.MediaType(), ranges)
+		if q > bestQ {
+			best = e
+			bestQ = q
+		}
+	}
+
+	if best == nil {
+		return nil, HTTPError{
+			Status:  http.StatusNotAcceptable,
+			Code:    "not_acceptable",
+			Message: "None of the media types in '" + accept + "' can be answered",
+		}
+	}
+
+	return best, nil
+}
+
+// Parse the media ranges of an Accept header
+func parseAccept(accept string) []mediaRange {
+	ranges := []mediaRange{}
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		slash := strings.IndexByte(mediaType, '/')
+		if slash < 0 {
+			continue // Invalid media range
+		}
+
+		r := mediaRange{
+			Type:    mediaType[:slash],
+			SubType: mediaType[slash+1:],
+			Q:       1,
+		}
+
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				q, err := strconv.ParseFloat(kv[1], 64)
+				if err == nil {
+					r.Q = q
+				}
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges
+}
+
+// Return the quality of the media type for the given ranges
+// The most specific range that matches the media type decides it
+func acceptQuality(mediaType string, ranges []mediaRange) float64 {
+	slash := strings.IndexByte(mediaType, '/')
+	t, sub := mediaType[:slash], mediaType[slash+1:]
+
+	q := 0.0
+	specificity := -1
+
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.Type == t && r.SubType == sub:
+			s = 2
+		case r.Type == t && r.SubType == "*":
+			s = 1
+		case r.Type == "*" && r.SubType == "*":
+			s = 0
+		}
+		if s > specificity {
+			specificity = s
+			q = r.Q
+		}
+	}
+
+	return q
+}
+
+// Wraps the ResponseWriter to write the status just before the body
+// So if the Encoder fails before writing anything, an error can still be answered
+type responseBody struct {
+	http.ResponseWriter
+	Status  int
+	Written bool
+}
+
+func (rb *responseBody) Write(p []byte) (int, error) {
+	if !rb.Written {
+		rb.Written = true
+		rb.ResponseWriter.WriteHeader(rb.Status)
+	}
+	return rb.ResponseWriter.Write(p)
+}
+
+// Send what was already written, so the client receive it while the rest is produced
+func (rb *responseBody) Flush() {
+	if !rb.Written {
+		rb.Written = true
+		rb.ResponseWriter.WriteHeader(rb.Status)
+	}
+	if f, ok := rb.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Encode the value with the given Encoder and write it with the status
+// If it fails before writing anything, the error is returned to be answered
+func writeResponse(w http.ResponseWriter, e Encoder, v interface{}, status int) error {
+
+	if _, ok := e.(streamEncoder); !ok {
+		v = collectValue(v)
+	}
+
+	w.Header().Set("Content-Type", e.MediaType())
+
+	body := &responseBody{ResponseWriter: w, Status: status}
+
+	err := e.Encode(body, v)
+	if err != nil {
+		if !body.Written {
+			return err
+		}
+		return nil // Too late, part of the response was already sent
+	}
+
+	// Nothing was encoded, but the status should be sent anyway
+	if !body.Written {
+		w.WriteHeader(status)
+	}
+
+	return nil
+}
+
+// Write the error body encoded with the given Encoder
+// It is encoded before writing, so a failure can still be answered
+func writeError(w http.ResponseWriter, e Encoder, body interface{}, status int) {
+
+	if _, ok := e.(streamEncoder); !ok {
+		body = collectValue(body)
+	}
+
+	buf := &bytes.Buffer{}
+
+	eerr := e.Encode(buf, body)
+	if eerr != nil {
+		http.Error(w, "{error: \"Error encoding the error message: "+eerr.Error()+"\"}", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", e.MediaType())
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// Return the Encoder used when the request doesn't accept any
+func defaultEncoder() Encoder {
+	encoders.RLock()
+	defer encoders.RUnlock()
+	return encoders.list[0]
+}
+
+// Call the function with each element of the Slice, Array, iterator or channel, one by one
+// Return false if the value is not a list, without calling the function
+// If the function fails, the remaining elements of a channel are received and discarded,
+// so its producer isn't blocked forever, it should close the channel when it ends
+func eachElem(v reflect.Value, fn func(reflect.Value) error) (bool, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false, nil
+		}
+		v = v.Elem()
+	}
+
+	// Iterators and channels are read until they end
+	if isSequence(v.Type()) {
+		if v.IsNil() {
+			return false, nil
+		}
+		for elem := range v.Seq() {
+			err := fn(elem)
+			if err != nil {
+				if v.Kind() == reflect.Chan {
+					go drain(v)
+				}
+				return true, err
+			}
+		}
+		return true, nil
+	}
+
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false, nil
+	}
+
+	// Bytes are encoded as a single value, like encoding/json does
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		return false, nil
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		err := fn(v.Index(i))
+		if err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// Receive the elements of the channel until it is closed
+func drain(ch reflect.Value) {
+	for {
+		_, ok := ch.Recv()
+		if !ok {
+			return
+		}
+	}
+}
+
+// Return true if the Type produces its elements in time:
+// an iterator like iter.Seq[T] or a channel that can be received
+func isSequence(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan:
+		return t.ChanDir()&reflect.RecvDir != 0
+	case reflect.Func:
+		return t.CanSeq()
+	}
+	return false
+}
+
+// Return the Type of the elements of a Slice, Array or sequence
+func elemOfListType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Func {
+		return t.In(0).In(0) // func(yield func(T) bool)
+	}
+	return t.Elem()
+}
+
+// The multiple outputs of a handler, by its names in the order they were returned
+// The JSON Encoder streams them in this order, the others receive a map
+type outputMap struct {
+	Names  []string
+	Values []interface{}
+}
+
+func (m *outputMap) add(name string, v interface{}) {
+	m.Names = append(m.Names, name)
+	m.Values = append(m.Values, v)
+}
+
+// Set the value, replacing the one with the same name
+func (m *outputMap) set(name string, v interface{}) {
+	for i, n := range m.Names {
+		if n == name {
+			m.Values[i] = v
+			return
+		}
+	}
+	m.add(name, v)
+}
+
+// Insert the value at the given position
+func (m *outputMap) insert(i int, name string, v interface{}) {
+	m.Names = append(m.Names[:i], append([]string{name}, m.Names[i:]...)...)
+	m.Values = append(m.Values[:i], append([]interface{}{v}, m.Values[i:]...)...)
+}
+
+func (m outputMap) get(name string) (interface{}, bool) {
+	for i, n := range m.Names {
+		if n == name {
+			return m.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+func (m outputMap) clone() outputMap {
+	return outputMap{
+		Names:  append([]string(nil), m.Names...),
+		Values: append([]interface{}(nil), m.Values...),
+	}
+}
+
+func (m outputMap) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := writeJSONOutputs(buf, m, "", "")
+	return buf.Bytes(), err
+}
+
+// Encoders that write the lists element by element
+// They receive the iterators, channels and outputs as they are,
+// the other Encoders receive them collected in Slices and maps
+type streamEncoder interface {
+	Encoder
+	streams()
+}
+
+// Collect the iterators, channels and outputs to be encoded at once
+func collectValue(v interface{}) interface{} {
+
+	if outputs, ok := v.(outputMap); ok {
+		m := make(map[string]interface{}, len(outputs.Names))
+		for i, name := range outputs.Names {
+			m[name] = collectValue(outputs.Values[i])
+		}
+		return m
+	}
+
+	value := reflect.ValueOf(v)
+	if !value.IsValid() || !isSequence(value.Type()) {
+		return v
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(elemOfListType(value.Type())), 0, 0)
+	eachElem(value, func(elem reflect.Value) error {
+		slice = reflect.Append(slice, elem)
+		return nil
+	})
+	return slice.Interface()
+}
+
+// Return the value as the generic types used by encoding/json:
+// map[string]interface{}, []interface{}, string, json.Number, bool and nil
+// So other Encoders follow the same rules of the JSON ones: json tags, omitempty...
+func genericValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	return generic, err
+}
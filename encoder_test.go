@@ -0,0 +1,168 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ShopAPI struct {
+	Books BookList
+}
+
+type BookList []Book
+
+type Book struct {
+	Title  string  `json:"title"`
+	Price  float64 `json:"price"`
+	Author *Author `json:"author,omitempty"`
+}
+
+type Author struct {
+	Name string `json:"name"`
+}
+
+func (l *BookList) GET() BookList {
+	return BookList{
+		{Title: "Dom Casmurro", Price: 10.5, Author: &Author{Name: "Machado"}},
+		{Title: "Untitled", Price: 0},
+	}
+}
+
+func (b *Book) GET(id *ID) (*Book, error) {
+	b.Title = "Book " + id.String()
+	return b, nil
+}
+
+func serveAccept(t *testing.T, ro *Route, uri, accept string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", accept)
+	ro.ServeHTTP(res, req)
+	return res
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		Accept    string
+		MediaType string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/xml", "application/xml"},
+		{"text/html, application/xml;q=0.9, */*;q=0.1", "application/xml"},
+		{"application/json;q=0.5, application/yaml", "application/yaml"},
+		{"text/*", "text/csv"},
+		{"application/x-ndjson, application/json;q=0.9", "application/x-ndjson"},
+		{"*/*, application/json;q=0", "application/xml"},
+	}
+
+	for _, test := range tests {
+		e, err := negotiate(test.Accept)
+		if err != nil {
+			t.Errorf("Accept %q: %s", test.Accept, err)
+			continue
+		}
+		if e.MediaType() != test.MediaType {
+			t.Errorf("Accept %q: expected %s, got %s", test.Accept, test.MediaType, e.MediaType())
+		}
+	}
+
+	_, err := negotiate("text/html")
+	if errorStatus(err) != http.StatusNotAcceptable {
+		t.Errorf("Expected status %d for text/html, got %v", http.StatusNotAcceptable, err)
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	route := newTestRoute(t, ShopAPI{})
+
+	tests := []struct {
+		URI      string
+		Accept   string
+		Status   int
+		Contains []string
+	}{
+		{"/shopapi/books", "application/json", http.StatusOK,
+			[]string{`"title": "Dom Casmurro"`}},
+		{"/shopapi/books", "application/xml", http.StatusOK,
+			[]string{"<response>", "<Title>Dom Casmurro</Title>"}},
+		{"/shopapi/books", "application/yaml", http.StatusOK,
+			[]string{`"title": "Dom Casmurro"`, `"price": 10.5`, `"name": "Machado"`}},
+		{"/shopapi/books", "text/csv", http.StatusOK,
+			[]string{"title,price,author.name\n", "Dom Casmurro,10.5,Machado\n", "Untitled,0,\n"}},
+		{"/shopapi/books/7", "text/csv", http.StatusOK,
+			[]string{"book\n", `"{""price"":0,""title"":""Book 7""}"`}},
+		{"/shopapi/books", "application/x-ndjson", http.StatusOK,
+			[]string{`{"title":"Dom Casmurro","price":10.5,"author":{"name":"Machado"}}` + "\n" +
+				`{"title":"Untitled","price":0}` + "\n"}},
+		// The multiple outputs map goes through the negotiation too
+		{"/shopapi/books/7", "application/xml", http.StatusOK,
+			[]string{"<response>", "<book>", "<Title>Book 7</Title>"}},
+		{"/shopapi/books", "text/html", http.StatusNotAcceptable,
+			[]string{`"code": "not_acceptable"`}},
+		// Errors are negotiated too
+		{"/shopapi/nothing", "application/yaml", http.StatusNotFound,
+			[]string{`"error":`}},
+	}
+
+	for _, test := range tests {
+		res := serveAccept(t, route, test.URI, test.Accept)
+		if res.Code != test.Status {
+			t.Errorf("%s %s: expected status %d, got %d: %s",
+				test.URI, test.Accept, test.Status, res.Code, res.Body)
+			continue
+		}
+		for _, s := range test.Contains {
+			if !strings.Contains(res.Body.String(), s) {
+				t.Errorf("%s %s: expected %q in the response:\n%s",
+					test.URI, test.Accept, s, res.Body)
+			}
+		}
+	}
+}
+
+type Shipment struct {
+	Code   string       `json:"code"`
+	Sent   time.Time    `json:"sent"`
+	To     Destination  `json:"to"`
+	Items  []string     `json:"items"`
+	Return *Destination `json:"return,omitempty"`
+}
+
+type Destination struct {
+	City string       `json:"city"`
+	Geo  *Coordinates `json:"geo"`
+}
+
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+func TestCSVColumns(t *testing.T) {
+	sent := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	shipments := []*Shipment{
+		{Code: "a", Sent: sent, To: Destination{City: "Recife", Geo: &Coordinates{-8.05, -34.9}}, Items: []string{"x", "y"}},
+		{Code: "b", To: Destination{City: "Natal"}, Return: &Destination{City: "Recife"}},
+	}
+
+	buf := &bytes.Buffer{}
+	err := CSVEncoder{}.Encode(buf, shipments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "code,sent,to.city,to.geo.lat,to.geo.lng,items,return.city,return.geo.lat,return.geo.lng\n" +
+		`a,2020-01-02T03:04:05Z,Recife,-8.05,-34.9,"[""x"",""y""]",,,` + "\n" +
+		"b,0001-01-01T00:00:00Z,Natal,,,,Recife,,\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf)
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+// Wraps every response body in an object, like:
+//
+//	{"data": ..., "meta": {"count": 2}, "links": {"next": "/api/bs?page=2"}}
+//
+// The errors are sent in the error member instead of the data
+// The meta and the links are only sent when there is some entry
+// Empty names use data, meta, links and error
+type Envelope struct {
+	Data  string
+	Meta  string
+	Links string
+	Error string
+}
+
+// Encoders that write each element of a list as a row, like CSV
+// The envelope isn't applied to them, it would be the single row
+type rowEncoder interface {
+	Encoder
+	rows()
+}
+
+// Return the data wrapped in the envelope with the Meta entries of the reply
+// A nil Envelope returns the data as it is
+func (env *Envelope) wrap(data interface{}, r *reply) interface{} {
+	if env == nil {
+		return data
+	}
+	if _, ok := r.Encoder.(rowEncoder); ok {
+		return data
+	}
+
+	body := outputMap{}
+	body.add(nameOr(env.Data, "data"), data)
+	env.addMeta(&body, r, true)
+	return body
+}
+
+// Return the error body wrapped in the envelope with the Meta entries
+// Inside the envelope the error message is sent as message
+func (env *Envelope) wrapError(e Encoder, err error, r *reply) interface{} {
+	body := errorBody(err)
+	if env == nil {
+		return body
+	}
+	if _, ok := e.(rowEncoder); ok {
+		return body
+	}
+
+	body["message"] = body["error"]
+	delete(body, "error")
+
+	wrapped := outputMap{}
+	wrapped.add(nameOr(env.Error, "error"), body)
+	env.addMeta(&wrapped, r, false)
+	return wrapped
+}
+
+// Add the Meta entries and links to the body
+// The links of the Route tree come first, when the Options ask for them
+func (env *Envelope) addMeta(body *outputMap, r *reply, routeLinks bool) {
+	entries, metaLinks := r.Meta.snapshot()
+
+	links := outputMap{}
+	if routeLinks && r.Route.options().Links {
+		for _, l := range r.Route.links(r.IDs) {
+			links.add(l.Rel, l.Href)
+		}
+	}
+	for i, rel := range metaLinks.Names {
+		links.set(rel, metaLinks.Values[i])
+	}
+
+	if len(entries.Names) > 0 {
+		body.add(nameOr(env.Meta, "meta"), entries)
+	}
+	if len(links.Names) > 0 {
+		body.add(nameOr(env.Links, "links"), links)
+	}
+}
+
+func nameOr(name, defaultName string) string {
+	if len(name) == 0 {
+		return defaultName
+	}
+	return name
+}
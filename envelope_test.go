@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type CatalogAPI struct {
+	Products ProductList
+}
+
+type ProductList []Product
+
+type Product struct {
+	Name string `json:"name"`
+}
+
+func (l *ProductList) Init(meta *Meta) {
+	meta.Set("source", "cache")
+}
+
+func (l *ProductList) GET(meta *Meta) ProductList {
+	meta.Set("count", 2)
+	meta.Warn("Prices are outdated")
+	meta.Link("next", "/catalogapi/products?page=2")
+	return ProductList{{Name: "Pen"}, {Name: "Ink"}}
+}
+
+func (p *Product) Init(id *ID, meta *Meta) error {
+	meta.Set("id", id.String())
+	if id.String() == "0" {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *Product) GET() (*Product, *Author) {
+	return &Product{Name: "Pen"}, &Author{Name: "Ana"}
+}
+
+func TestEnvelope(t *testing.T) {
+	route := newTestRoute(t, CatalogAPI{})
+
+	// Without an envelope the meta entries are not sent
+	res := serve(t, route, "GET", "/catalogapi/products")
+	body := &bytes.Buffer{}
+	json.Compact(body, res.Body.Bytes())
+	if body.String() != `[{"name":"Pen"},{"name":"Ink"}]` {
+		t.Errorf("Unexpected response without envelope: %s", body)
+	}
+
+	route.Options = &Options{Envelope: &Envelope{}}
+
+	tests := []struct {
+		URI      string
+		Status   int
+		Expected string
+	}{
+		{"/catalogapi/products", http.StatusOK,
+			`{"data":[{"name":"Pen"},{"name":"Ink"}],` +
+				`"meta":{"source":"cache","count":2,"warnings":["Prices are outdated"]},` +
+				`"links":{"next":"/catalogapi/products?page=2"}}`},
+		{"/catalogapi/products/7", http.StatusOK,
+			`{"data":{"product":{"name":"Pen"},"author":{"name":"Ana"}},"meta":{"id":"7"}}`},
+		{"/catalogapi/products/0", http.StatusNotFound,
+			`{"error":{"code":"not_found","message":"Resource not found"},"meta":{"id":"0"}}`},
+		{"/catalogapi/nothing", http.StatusNotFound,
+			`{"error":{"message":"Not found any handler for [GET] /catalogapi/nothing in the Route: [catalogapi] *api.CatalogAPI"}}`},
+	}
+
+	for _, test := range tests {
+		res := serve(t, route, "GET", test.URI)
+		if res.Code != test.Status {
+			t.Errorf("%s: expected status %d, got %d: %s", test.URI, test.Status, res.Code, res.Body)
+			continue
+		}
+		body := &bytes.Buffer{}
+		json.Compact(body, res.Body.Bytes())
+		if body.String() != test.Expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.URI, test.Expected, body)
+		}
+	}
+
+	// The members can be renamed, and the envelope goes through the negotiation
+	route.Options = &Options{Envelope: &Envelope{Data: "result", Meta: "info"}}
+
+	res = serveAccept(t, route, "/catalogapi/products", "application/xml")
+	for _, s := range []string{"<result>", "<info>", "<links>"} {
+		if !strings.Contains(res.Body.String(), s) {
+			t.Errorf("Expected %s in the XML response:\n%s", s, res.Body)
+		}
+	}
+
+	// Rows are sent without the envelope
+	res = serveAccept(t, route, "/catalogapi/products", "text/csv")
+	if res.Body.String() != "name\nPen\nInk\n" {
+		t.Errorf("Unexpected CSV response:\n%s", res.Body)
+	}
+}
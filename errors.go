@@ -0,0 +1,77 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+)
+
+// An error that decides the status and the body of the response
+// When returned from any Init method it stops the chain,
+// so the handler doesn't need to check its error argument
+// Ex: return api.HTTPError{Status: 409, Code: "conflict", Message: "Name in use"}
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// Returned by Init methods when the requested resource doesn't exist
+var ErrNotFound = HTTPError{
+	Status:  http.StatusNotFound,
+	Code:    "not_found",
+	Message: "Resource not found",
+}
+
+func (e HTTPError) Error() string {
+	if len(e.Message) > 0 {
+		return e.Message
+	}
+	return http.StatusText(e.StatusCode())
+}
+
+// Return the HTTP status of this error, 500 if it wasn't defined
+func (e HTTPError) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// Errors that know which HTTP status they should be answered with
+type statusError interface {
+	StatusCode() int
+}
+
+// Return the HTTP status for the given error
+// If it doesn't know its status, it is an Internal Server Error
+func errorStatus(err error) int {
+	var se statusError
+	if errors.As(err, &se) {
+		return se.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// Return the HTTPError wrapped in the given error
+// It accepts both HTTPError and *HTTPError
+func asHTTPError(err error) (HTTPError, bool) {
+	var e HTTPError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	var ptr *HTTPError
+	if errors.As(err, &ptr) && ptr != nil {
+		return *ptr, true
+	}
+	return HTTPError{}, false
+}
+
+// Return the body used to answer the given error
+// HTTPError also sends its Code, the message keeps the context wrapping it
+func errorBody(err error) map[string]string {
+	body := map[string]string{"error": err.Error()}
+	if e, ok := asHTTPError(err); ok && len(e.Code) > 0 {
+		body["code"] = e.Code
+	}
+	return body
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// How a request would be answered by the Route tree, step by step
+// It is meant to understand why a path is not found, or is found by another handler
+type Explanation struct {
+	HTTPMethod string `json:"method"`
+	Path       string `json:"path"`
+
+	// Each segment of the path and what it matched
+	Steps []ExplainStep `json:"steps"`
+
+	// The IDs caught in the path, by the Type of the resource they identify
+	IDs map[string]string `json:"ids"`
+
+	// The method that would answer, and the dependencies constructed before it, in order
+	Handler      string   `json:"handler,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// Why no handler was found
+	Error string `json:"error,omitempty"`
+
+	// The endpoint found, nil if none
+	Endpoint *Endpoint `json:"-"`
+}
+
+// What a segment of the path matched
+type ExplainStep struct {
+	Segment string `json:"segment"`
+	Match   string `json:"match"` // root, child, id, action, handler or none
+	Route   string `json:"route"` // The path template of the Route
+	Detail  string `json:"detail,omitempty"`
+}
+
+// Explain how the request would be answered, without running anything
+// A path without the leading slash is explained as if it had one
+func (ro *Route) Explain(httpMethod, path string) Explanation {
+
+	path = strings.Split(path, "?")[0]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	e := Explanation{
+		HTTPMethod: httpMethod,
+		Path:       path,
+		Steps:      []ExplainStep{},
+		IDs:        map[string]string{},
+	}
+
+	segments := strings.Split(path, "/")[1:]
+	template, _ := ro.path(idMap{})
+
+	if segments[0] != ro.Name {
+		e.Steps = append(e.Steps, ExplainStep{Segment: segments[0], Match: "none", Route: template,
+			Detail: "the root Route is " + ro.Name})
+	} else {
+		e.Steps = append(e.Steps, ExplainStep{Segment: ro.Name, Match: "root", Route: template})
+		steps, _ := ro.explain(segments[1:], httpMethod)
+		e.Steps = append(e.Steps, steps...)
+	}
+
+	// The handler is found as the requests find it
+	ids := idMap{}
+	h, err := ro.handler(path, httpMethod, ids)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+
+	for t, id := range ids {
+		e.IDs[t.String()] = id.Interface().(*ID).String()
+	}
+
+	endpoint := h.endpoint()
+	e.Endpoint = &endpoint
+	e.Handler = funcName(endpoint)
+	for _, d := range endpoint.Dependencies {
+		line := d.Type.String()
+		if d.Init != nil {
+			line += " Init(" + strings.Join(typeNames(initInputs(d.Init)), ", ") + ")"
+		}
+		e.Dependencies = append(e.Dependencies, line)
+	}
+
+	return e
+}
+
+// Return the steps that match the segments from this Route, like the radix tree does:
+// actions and children first, then the ID of a list
+// When nothing is found, the steps are the ones of the deepest try
+func (ro *Route) explain(segments []string, httpMethod string) ([]ExplainStep, bool) {
+
+	template, _ := ro.path(idMap{})
+
+	// The end of the path, or a trailing slash, is answered by the main handler
+	if len(segments) == 0 || len(segments) == 1 && len(segments[0]) == 0 {
+		if h, exist := ro.Handlers[httpMethod]; exist {
+			return []ExplainStep{{Match: "handler", Route: template, Detail: h.Method.Method.Name}}, true
+		}
+		return []ExplainStep{{Match: "none", Route: template, Detail: ro.missingHandler(httpMethod, "")}}, false
+	}
+
+	segment := segments[0]
+	deepest := []ExplainStep{}
+
+	if len(segments) == 1 {
+		if h, exist := ro.Handlers[httpMethod+segment]; exist {
+			return []ExplainStep{{Segment: segment, Match: "action", Route: template, Detail: h.Method.Method.Name}}, true
+		}
+	}
+
+	if child, exist := ro.Children[segment]; exist {
+		childTemplate, _ := child.path(idMap{})
+		steps, ok := child.explain(segments[1:], httpMethod)
+		steps = append([]ExplainStep{{Segment: segment, Match: "child", Route: childTemplate}}, steps...)
+		if ok {
+			return steps, true
+		}
+		deepest = steps
+	}
+
+	if ro.IsSlice && ro.Elem != nil {
+		elemTemplate, _ := ro.Elem.path(idMap{})
+		steps, ok := ro.Elem.explain(segments[1:], httpMethod)
+		steps = append([]ExplainStep{{Segment: segment, Match: "id", Route: elemTemplate,
+			Detail: "ID of " + ro.Elem.Value.Type().String()}}, steps...)
+		if ok || len(steps) > len(deepest) {
+			return steps, ok
+		}
+	}
+
+	if len(deepest) > 0 {
+		return deepest, false
+	}
+
+	return []ExplainStep{{Segment: segment, Match: "none", Route: template, Detail: ro.missingHandler(httpMethod, segment)}}, false
+}
+
+// Return why the segment matched nothing in this Route
+func (ro *Route) missingHandler(httpMethod, segment string) string {
+
+	methods := []string{}
+	actions := []string{}
+	for _, h := range ro.Handlers {
+		if h.Method.Name == segment {
+			methods = append(methods, h.Method.HTTPMethod)
+		}
+		if len(h.Method.Name) > 0 && h.Method.HTTPMethod == httpMethod {
+			actions = append(actions, h.Method.Name)
+		}
+	}
+	children := []string{}
+	for name := range ro.Children {
+		children = append(children, name)
+	}
+	sort.Strings(methods)
+	sort.Strings(actions)
+	sort.Strings(children)
+
+	if len(methods) > 0 {
+		return fmt.Sprintf("no %s handler, only %s", httpMethod, strings.Join(methods, ", "))
+	}
+	if len(segment) == 0 {
+		return fmt.Sprintf("no %s handler", httpMethod)
+	}
+	detail := fmt.Sprintf("not a child nor a %s action", httpMethod)
+	known := []string{}
+	if len(children) > 0 {
+		known = append(known, "children: "+strings.Join(children, ", "))
+	}
+	if len(actions) > 0 {
+		known = append(known, httpMethod+" actions: "+strings.Join(actions, ", "))
+	}
+	if len(known) > 0 {
+		detail += " (" + strings.Join(known, "; ") + ")"
+	}
+	return detail
+}
+
+// One line per step, followed by the IDs, the handler and its dependencies
+func (e Explanation) String() string {
+	lines := []string{e.HTTPMethod + " " + e.Path}
+
+	for _, s := range e.Steps {
+		line := fmt.Sprintf("\t%-10s %-8s %s", s.Segment, s.Match, s.Route)
+		if len(s.Detail) > 0 {
+			line += ": " + s.Detail
+		}
+		lines = append(lines, line)
+	}
+
+	if len(e.Error) > 0 {
+		return strings.Join(append(lines, "error: "+e.Error), "\n")
+	}
+
+	types := []string{}
+	for t := range e.IDs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		lines = append(lines, fmt.Sprintf("id: %s = %s", t, e.IDs[t]))
+	}
+
+	lines = append(lines, "handler: "+e.Handler)
+	for _, d := range e.Dependencies {
+		lines = append(lines, "\tconstructs "+d)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Return a handler that explains the requests given by the method and path query parameters
+// Ex: /debug/routes?method=GET&path=/api/a/bs/7
+// It is meant for debugging, it shows the inner wiring of the API
+func ExplainHandler(ro *Route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := newReply(w, req, ro)
+
+		query := req.URL.Query()
+		httpMethod := query.Get("method")
+		if len(httpMethod) == 0 {
+			httpMethod = http.MethodGet
+		}
+		path := query.Get("path")
+		if !strings.HasPrefix(path, "/") {
+			r.error(HTTPError{Status: http.StatusBadRequest, Code: "bad_request",
+				Message: "The path to explain should start with /"}, http.StatusBadRequest)
+			return
+		}
+
+		err := r.negotiate()
+		if err != nil {
+			r.error(err, errorStatus(err))
+			return
+		}
+
+		r.send(ro.Explain(httpMethod, path), http.StatusOK)
+	})
+}
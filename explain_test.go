@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+
+	tests := []struct {
+		Method   string
+		Path     string
+		Expected string
+	}{
+		{"GET", "/routerapi/items/7/parts/3", `GET /routerapi/items/7/parts/3
+	routerapi  root     /routerapi
+	items      child    /routerapi/items
+	7          id       /routerapi/items/{id}: ID of *api.Item
+	parts      child    /routerapi/items/{id}/parts
+	3          id       /routerapi/items/{id}/parts/{id}: ID of *api.Part
+	           handler  /routerapi/items/{id}/parts/{id}: GET
+id: *api.Item = 7
+id: *api.Part = 3
+handler: (*api.Part).GET(*api.ID) *api.Answer
+	constructs *api.Part`},
+		{"GET", "/routerapi/items/login?page=2", `GET /routerapi/items/login
+	routerapi  root     /routerapi
+	items      child    /routerapi/items
+	login      action   /routerapi/items: GETLogin
+handler: (*api.ItemList).GETLogin() *api.Answer
+	constructs *api.ItemList`},
+		{"POST", "/routerapi/items/7", `POST /routerapi/items/7
+	routerapi  root     /routerapi
+	items      child    /routerapi/items
+	7          id       /routerapi/items/{id}: ID of *api.Item
+	           none     /routerapi/items/{id}: no POST handler, only GET, PUT
+error: Not found any handler for [POST] /routerapi/items/7 in the Route: [routerapi] *api.RouterAPI`},
+		{"GET", "/routerapi/profile/nothing", `GET /routerapi/profile/nothing
+	routerapi  root     /routerapi
+	profile    child    /routerapi/profile
+	nothing    none     /routerapi/profile: not a child nor a GET action (children: settings; GET actions: login)
+error: Not found any handler for [GET] /routerapi/profile/nothing in the Route: [routerapi] *api.RouterAPI`},
+		{"GET", "/other", `GET /other
+	other      none     /routerapi: the root Route is routerapi
+error: Not found any handler for [GET] /other in the Route: [routerapi] *api.RouterAPI`},
+		{"GET", "", `GET /
+	           none     /routerapi: the root Route is routerapi
+error: Not found any handler for [GET] / in the Route: [routerapi] *api.RouterAPI`},
+		{"GET", "routerapi", `GET /routerapi
+	routerapi  root     /routerapi
+	           none     /routerapi: no GET handler
+error: Not found any handler for [GET] /routerapi in the Route: [routerapi] *api.RouterAPI`},
+	}
+
+	for _, test := range tests {
+		e := route.Explain(test.Method, test.Path)
+		if e.String() != test.Expected {
+			t.Errorf("Expected the explanation\n%s\ngot\n%s", test.Expected, e)
+		}
+	}
+}
+
+func TestExplainHandler(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+	debug := ExplainHandler(route)
+
+	query := url.Values{"method": {"PUT"}, "path": {"/routerapi/items/7"}}
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/debug/routes?"+query.Encode(), nil)
+	debug.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected the status 200, got %d %s", res.Code, res.Body)
+	}
+
+	e := Explanation{}
+	err := json.Unmarshal(res.Body.Bytes(), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Handler != "(*api.Item).PUT(*api.ID) *api.Answer" || !reflect.DeepEqual(e.IDs, map[string]string{"*api.Item": "7"}) {
+		t.Errorf("Unexpected explanation %+v", e)
+	}
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/debug/routes?path=routerapi", nil)
+	debug.ServeHTTP(res, req)
+	if res.Code != http.StatusBadRequest {
+		t.Errorf("Expected the status 400 for a relative path, got %d", res.Code)
+	}
+}
+
+// The steps are matched apart from the radix tree,
+// they should end in the handler the tree finds for every path of the test APIs
+func TestExplainMatchesTree(t *testing.T) {
+	apis := []interface{}{
+		API{}, ArchiveAPI{}, CatalogAPI{}, ClubAPI{}, DeepAPI{}, GuardAPI{}, InitAPI{},
+		JournalAPI{}, LibraryAPI{}, MemberAPI{}, ParallelAPI{}, RouterAPI{}, SessionAPI{},
+		ShopAPI{}, SlowAPI{}, StoreAPI{}, StreamAPI{}, StrictAPI{}, WarehouseAPI{},
+	}
+
+	for _, api := range apis {
+		route := newTestRoute(t, api)
+
+		// The IDs are also tried with the names of the children and actions,
+		// where the order the tree tries them matters
+		templates := []string{}
+		methods := map[string]bool{}
+		names := map[string]bool{"7": true}
+		route.Walk(func(e Endpoint) error {
+			templates = append(templates, e.Path)
+			methods[e.HTTPMethod] = true
+			for _, segment := range strings.Split(e.Path, "/") {
+				if len(segment) > 0 && segment != "{id}" {
+					names[segment] = true
+				}
+			}
+			return nil
+		})
+
+		paths := []string{}
+		for _, template := range templates {
+			expanded := []string{template}
+			for strings.Contains(expanded[0], "{id}") {
+				next := []string{}
+				for _, path := range expanded {
+					for name := range names {
+						next = append(next, strings.Replace(path, "{id}", name, 1))
+					}
+				}
+				expanded = next
+			}
+			for _, path := range expanded {
+				paths = append(paths, path, path+"/", path+"/other")
+			}
+		}
+
+		for _, path := range paths {
+			for method := range methods {
+				explainMatchesTree(t, route, method, path)
+			}
+		}
+	}
+}
+
+func explainMatchesTree(t *testing.T, route *Route, method, path string) {
+	t.Helper()
+
+	e := route.Explain(method, path)
+	last := e.Steps[len(e.Steps)-1]
+	found := last.Match == "handler" || last.Match == "action"
+
+	h, err := route.handler(path, method, idMap{})
+	if err != nil {
+		if found {
+			t.Errorf("%s %s: explained as %s, but the tree found nothing", method, path, last.Detail)
+		}
+		return
+	}
+
+	template, _ := h.Route.path(idMap{})
+	if !found || last.Detail != h.Method.Method.Name || last.Route != template {
+		t.Errorf("%s %s: explained as\n%s\nbut the tree found %s", method, path, e, h)
+		return
+	}
+
+	// The IDs of the steps are the ones the tree caught
+	ids := map[string]string{}
+	for _, s := range e.Steps {
+		if s.Match == "id" {
+			ids[strings.TrimPrefix(s.Detail, "ID of ")] = s.Segment
+		}
+	}
+	if !reflect.DeepEqual(ids, e.IDs) {
+		t.Errorf("%s %s: explained the IDs %v, but the tree caught %v", method, path, ids, e.IDs)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A Struct field and how encoding/json names it
+// Used so every media type follows the same rules of the JSON one
+type jsonField struct {
+	Name      string
+	Index     []int
+	Type      reflect.Type
+	OmitEmpty bool
+}
+
+// The fields already found for each Struct Type
+var jsonFieldsCache sync.Map
+
+// Return the fields of the Struct Type as encoding/json sees them
+// Fields of anonymous Structs without name are promoted, like json does
+func jsonFields(t reflect.Type) []jsonField {
+	cached, exist := jsonFieldsCache.Load(t)
+	if exist {
+		return cached.([]jsonField)
+	}
+
+	fields := appendJSONFields([]jsonField{}, t, nil)
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+func appendJSONFields(fields []jsonField, t reflect.Type, index []int) []jsonField {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if comma := strings.IndexByte(tag, ','); comma >= 0 {
+			name, opts = tag[:comma], tag[comma:]
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+
+		// Promote the fields of anonymous Structs
+		if field.Anonymous && len(name) == 0 {
+			ft := elemOfType(field.Type)
+			if ft.Kind() == reflect.Struct {
+				fields = appendJSONFields(fields, ft, fieldIndex)
+				continue
+			}
+		}
+
+		if !isExportedField(field) {
+			continue
+		}
+
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		fields = append(fields, jsonField{
+			Name:      name,
+			Index:     fieldIndex,
+			Type:      field.Type,
+			OmitEmpty: strings.Contains(opts, ",omitempty"),
+		})
+	}
+
+	return fields
+}
+
+// Return the Value of the field to be set
+// Allocating the Ptrs to anonymous Structs on the way
+func settableField(v reflect.Value, f jsonField) reflect.Value {
+	for _, i := range f.Index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// Return the Value of the field, or an invalid Value
+// if it is inside a nil Ptr to an anonymous Struct
+func fieldValue(v reflect.Value, f jsonField) reflect.Value {
+	for _, i := range f.Index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// Return true if the Value is empty by the omitempty rules
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Max memory used to parse multipart bodies,
+// the rest of the files is stored in temporary files
+const multipartMemory = 32 << 20
+
+// A file sent in a multipart/form-data request
+// Handlers and Init methods receive them asking for api.File or []api.File
+type File struct {
+	Field    string // The name of the form field
+	Filename string
+	Size     int64
+	Header   textproto.MIMEHeader
+
+	header *multipart.FileHeader
+}
+
+var (
+	fileSliceType = reflect.TypeOf([]File(nil))
+	fileType      = fileSliceType.Elem()
+)
+
+// Open the content of the file
+func (f File) Open() (multipart.File, error) {
+	if f.header == nil {
+		return nil, errors.New("No file was sent")
+	}
+	return f.header.Open()
+}
+
+// Decodes the request bodies of HTML forms
+// The fields are found by the same names used in JSON,
+// the fields of inner Structs are separated by dots, like author.name
+type FormDecoder struct{}
+
+func (d FormDecoder) MediaType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+func (d FormDecoder) Decode(req *http.Request, v interface{}) error {
+	err := req.ParseForm()
+	if err != nil {
+		return err
+	}
+	return bindValues(req.PostForm, reflect.ValueOf(v), "")
+}
+
+// Decodes the multipart/form-data request bodies
+// The values are read like the FormDecoder does
+// and the files are given to who asks for api.File or []api.File
+type MultipartDecoder struct{}
+
+func (d MultipartDecoder) MediaType() string {
+	return "multipart/form-data"
+}
+
+func (d MultipartDecoder) Decode(req *http.Request, v interface{}) error {
+	err := req.ParseMultipartForm(multipartMemory)
+	if err != nil {
+		return err
+	}
+	return bindValues(req.MultipartForm.Value, reflect.ValueOf(v), "")
+}
+
+// Return the files sent in the request, in the order of its fields
+// The multipart body is parsed if it wasn't yet
+func requestFiles(req *http.Request) []File {
+
+	if req.MultipartForm == nil {
+		if !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+			return []File{}
+		}
+		err := req.ParseMultipartForm(multipartMemory)
+		if err != nil {
+			return []File{}
+		}
+	}
+
+	fields := make([]string, 0, len(req.MultipartForm.File))
+	for field := range req.MultipartForm.File {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	files := []File{}
+	for _, field := range fields {
+		for _, h := range req.MultipartForm.File[field] {
+			files = append(files, File{
+				Field:    field,
+				Filename: h.Filename,
+				Size:     h.Size,
+				Header:   h.Header,
+				header:   h,
+			})
+		}
+	}
+
+	return files
+}
+
+// Set the values of the form in the fields of the given Value
+// The names of inner Structs fields start with the given prefix
+func bindValues(values url.Values, v reflect.Value, prefix string) error {
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("Forms can't be decoded in %s", v.Type())
+	}
+
+	for _, f := range jsonFields(v.Type()) {
+		name := prefix + f.Name
+
+		// Inner Structs have its fields separated by dots
+		if elemOfType(f.Type).Kind() == reflect.Struct {
+			if hasPrefix(values, name+".") {
+				err := bindValues(values, settableField(v, f), name+".")
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		fieldValues, exist := values[name]
+		if !exist || len(fieldValues) == 0 {
+			continue
+		}
+
+		err := setString(settableField(v, f), fieldValues)
+		if err != nil {
+			return fmt.Errorf("Field %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Return true if some value name starts with the prefix
+func hasPrefix(values url.Values, prefix string) bool {
+	for name := range values {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set the text values in the given Value, converting it to the Value Kind
+// Slices receive all the values, other Kinds receive the first one
+func setString(v reflect.Value, texts []string) error {
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setString(v.Elem(), texts)
+
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(texts), len(texts))
+		for i, text := range texts {
+			err := setString(slice.Index(i), []string{text})
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+
+	case reflect.String:
+		v.SetString(texts[0])
+		return nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(texts[0])
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(texts[0], 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(texts[0], 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(texts[0], v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+		return nil
+	}
+
+	return fmt.Errorf("Type %s can't be read from a form", v.Type())
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The formats the graphs are written in
+type GraphFormat int
+
+const (
+	// Graphviz, https://graphviz.org
+	DOT GraphFormat = iota
+	// Mermaid flowcharts, https://mermaid.js.org
+	Mermaid
+)
+
+// A graph to be written in some GraphFormat
+type graph struct {
+	Nodes []graphNode
+	Edges []graphEdge
+}
+
+type graphNode struct {
+	Lines []string
+}
+
+type graphEdge struct {
+	From, To int
+	Label    string
+	Dashed   bool
+}
+
+// Add a node and return its index
+func (g *graph) node(lines ...string) int {
+	g.Nodes = append(g.Nodes, graphNode{Lines: lines})
+	return len(g.Nodes) - 1
+}
+
+func (g *graph) edge(from, to int, label string, dashed bool) {
+	g.Edges = append(g.Edges, graphEdge{From: from, To: to, Label: label, Dashed: dashed})
+}
+
+// Write the graph in the format, the nodes are named n0, n1...
+func (g *graph) write(w io.Writer, format GraphFormat) error {
+	lines := []string{}
+
+	switch format {
+	case DOT:
+		lines = append(lines, "digraph {")
+		for i, n := range g.Nodes {
+			lines = append(lines, fmt.Sprintf("\tn%d [label=%s];", i, strconv.Quote(strings.Join(n.Lines, "\n"))))
+		}
+		for _, e := range g.Edges {
+			attrs := []string{}
+			if len(e.Label) > 0 {
+				attrs = append(attrs, "label="+strconv.Quote(e.Label))
+			}
+			if e.Dashed {
+				attrs = append(attrs, "style=dashed")
+			}
+			line := fmt.Sprintf("\tn%d -> n%d", e.From, e.To)
+			if len(attrs) > 0 {
+				line += " [" + strings.Join(attrs, ", ") + "]"
+			}
+			lines = append(lines, line+";")
+		}
+		lines = append(lines, "}")
+
+	case Mermaid:
+		lines = append(lines, "flowchart TD")
+		for i, n := range g.Nodes {
+			label := mermaidEscaper.Replace(strings.Join(n.Lines, "<br/>"))
+			lines = append(lines, fmt.Sprintf("\tn%d[\"%s\"]", i, label))
+		}
+		for _, e := range g.Edges {
+			arrow := "-->"
+			if e.Dashed {
+				arrow = "-.->"
+			}
+			if len(e.Label) > 0 {
+				arrow += "|" + mermaidEscaper.Replace(e.Label) + "|"
+			}
+			lines = append(lines, fmt.Sprintf("\tn%d %s n%d", e.From, arrow, e.To))
+		}
+
+	default:
+		return fmt.Errorf("Unknown graph format %d", format)
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// The characters Mermaid reads as part of its syntax, written as entity codes
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "{", "#123;", "}", "#125;", "|", "#124;")
+
+// Write the Resource tree: its children, the Elem of the Slices and the Resources it extends
+func WriteResourceGraph(w io.Writer, r *Resource, format GraphFormat) error {
+	g := &graph{}
+	g.addResource(r)
+	return g.write(w, format)
+}
+
+func (g *graph) addResource(r *Resource) int {
+	n := g.node(r.Name, r.Value.Type().String())
+
+	if r.IsSlice && r.Elem != nil {
+		g.edge(n, g.addResource(r.Elem), "elem", true)
+	}
+	for _, e := range r.Extends {
+		g.edge(n, g.addResource(e), "extends", true)
+	}
+	for _, c := range r.Children {
+		g.edge(n, g.addResource(c), "", false)
+	}
+
+	return n
+}
+
+// Write the Route tree, each Route with the path template and its handlers
+func WriteRouteGraph(w io.Writer, ro *Route, format GraphFormat) error {
+	g := &graph{}
+	g.addRoute(ro)
+	return g.write(w, format)
+}
+
+func (g *graph) addRoute(ro *Route) int {
+	path, _ := ro.path(idMap{})
+	lines := []string{path, ro.Value.Type().String()}
+
+	keys := []string{}
+	for key := range ro.Handlers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h := ro.Handlers[key]
+		lines = append(lines, strings.TrimSpace(h.Method.HTTPMethod+" "+h.Method.Name))
+	}
+
+	n := g.node(lines...)
+
+	if ro.IsSlice && ro.Elem != nil {
+		g.edge(n, g.addRoute(ro.Elem), "{id}", true)
+	}
+
+	names := []string{}
+	for name := range ro.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.edge(n, g.addRoute(ro.Children[name]), "", false)
+	}
+
+	return n
+}
+
+// Write the dependencies of the endpoint, from the handler to the Init methods they call
+// The edges go from who asks to what is asked
+func WriteDependencyGraph(w io.Writer, e Endpoint, format GraphFormat) error {
+	g := &graph{}
+
+	handler := g.node(e.HTTPMethod+" "+e.Path, funcName(e))
+
+	nodes := make([]int, len(e.Dependencies))
+	for i, d := range e.Dependencies {
+		lines := []string{d.Type.String()}
+		if d.Init != nil {
+			lines = append(lines, "Init("+strings.Join(typeNames(initInputs(d.Init)), ", ")+")")
+		}
+		nodes[i] = g.node(lines...)
+	}
+
+	// The handler asks for the resource that owns it and its inputs
+	for _, t := range append([]reflect.Type{e.Resource}, e.Inputs...) {
+		if i := dependencyIndex(e.Dependencies, t); i >= 0 {
+			g.edge(handler, nodes[i], "", false)
+		}
+	}
+
+	for i, d := range e.Dependencies {
+		if d.Init == nil {
+			continue
+		}
+		for _, t := range initInputs(d.Init) {
+			if j := dependencyIndex(e.Dependencies, t); j >= 0 && j != i {
+				g.edge(nodes[i], nodes[j], "Init", false)
+			}
+		}
+	}
+
+	return g.write(w, format)
+}
+
+// Return the index of the dependency constructed for the Type, or -1
+func dependencyIndex(ds []Dependency, t reflect.Type) int {
+	for i, d := range ds {
+		if d.Type == ptrOfType(t) {
+			return i
+		}
+	}
+	if t.Kind() == reflect.Interface {
+		for i, d := range ds {
+			if d.Type.Implements(t) {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRouteGraph(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+
+	tests := []struct {
+		Format   GraphFormat
+		Expected string
+	}{
+		{DOT, `digraph {
+	n0 [label="/routerapi\n*api.RouterAPI"];
+	n1 [label="/routerapi/items\n*api.ItemList\nGET\nGET login"];
+	n2 [label="/routerapi/items/{id}\n*api.Item\nGET\nPUT"];
+	n3 [label="/routerapi/items/{id}/parts\n*api.PartList"];
+	n4 [label="/routerapi/items/{id}/parts/{id}\n*api.Part\nGET"];
+	n5 [label="/routerapi/profile\n*api.Profile\nGET\nGET login"];
+	n6 [label="/routerapi/profile/settings\n*api.Settings\nGET"];
+	n3 -> n4 [label="{id}", style=dashed];
+	n2 -> n3;
+	n1 -> n2 [label="{id}", style=dashed];
+	n0 -> n1;
+	n5 -> n6;
+	n0 -> n5;
+}
+`},
+		{Mermaid, `flowchart TD
+	n0["/routerapi<br/>*api.RouterAPI"]
+	n1["/routerapi/items<br/>*api.ItemList<br/>GET<br/>GET login"]
+	n2["/routerapi/items/#123;id#125;<br/>*api.Item<br/>GET<br/>PUT"]
+	n3["/routerapi/items/#123;id#125;/parts<br/>*api.PartList"]
+	n4["/routerapi/items/#123;id#125;/parts/#123;id#125;<br/>*api.Part<br/>GET"]
+	n5["/routerapi/profile<br/>*api.Profile<br/>GET<br/>GET login"]
+	n6["/routerapi/profile/settings<br/>*api.Settings<br/>GET"]
+	n3 -.->|#123;id#125;| n4
+	n2 --> n3
+	n1 -.->|#123;id#125;| n2
+	n0 --> n1
+	n5 --> n6
+	n0 --> n5
+`},
+	}
+
+	for _, test := range tests {
+		graph := &bytes.Buffer{}
+		err := WriteRouteGraph(graph, route, test.Format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if graph.String() != test.Expected {
+			t.Errorf("Expected the graph\n%s\ngot\n%s", test.Expected, graph)
+		}
+	}
+}
+
+func TestResourceGraph(t *testing.T) {
+	resource, err := NewResource(RouterAPI{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `digraph {
+	n0 [label="routerapi\n*api.RouterAPI"];
+	n1 [label="items\n*api.ItemList"];
+	n2 [label="items\n*api.Item"];
+	n3 [label="parts\n*api.PartList"];
+	n4 [label="parts\n*api.Part"];
+	n5 [label="profile\n*api.Profile"];
+	n6 [label="settings\n*api.Settings"];
+	n3 -> n4 [label="elem", style=dashed];
+	n2 -> n3;
+	n1 -> n2 [label="elem", style=dashed];
+	n0 -> n1;
+	n5 -> n6;
+	n0 -> n5;
+}
+`
+
+	graph := &bytes.Buffer{}
+	WriteResourceGraph(graph, resource, DOT)
+	if graph.String() != expected {
+		t.Errorf("Expected the graph\n%s\ngot\n%s", expected, graph)
+	}
+}
+
+func TestDependencyGraph(t *testing.T) {
+	route := newTestRoute(t, API{})
+
+	expected := `flowchart TD
+	n0["GET /api/a/bs/#123;id#125;/cs/#123;id#125;<br/>(*api.C).GET() *api.C"]
+	n1["*api.B<br/>Init(*api.ID)"]
+	n2["*api.A<br/>Init()"]
+	n3["*api.C<br/>Init(*api.ID, api.B, api.A)"]
+	n0 --> n3
+	n3 -->|Init| n1
+	n3 -->|Init| n2
+`
+
+	graph := &bytes.Buffer{}
+	route.Walk(func(e Endpoint) error {
+		if e.Path == "/api/a/bs/{id}/cs/{id}" {
+			return WriteDependencyGraph(graph, e, Mermaid)
+		}
+		return nil
+	})
+	if graph.String() != expected {
+		t.Errorf("Expected the graph\n%s\ngot\n%s", expected, graph)
+	}
+}
+
+type CycleAPI struct {
+	Nest Nest
+}
+
+type Nest struct{}
+
+func (n *Nest) GET(e Egg) {}
+
+type Egg struct{}
+
+func (e *Egg) Init(c Chicken) {}
+
+type Chicken struct{}
+
+func (c *Chicken) Init(e Egg) {}
+
+func TestCircularDependencyGraph(t *testing.T) {
+	resource, err := NewResource(CycleAPI{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewRoute(resource)
+
+	cycle := &CircularDependencyError{}
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Expected a CircularDependencyError, got %v", err)
+	}
+
+	message := "*api.Egg depends on *api.Chicken that depends on *api.Egg\n"
+	if err.Error() != message {
+		t.Errorf("Expected the message %q, got %q", message, err.Error())
+	}
+
+	expected := `digraph {
+	n0 [label="*api.Egg"];
+	n1 [label="*api.Chicken"];
+	n0 -> n1 [label="depends on"];
+	n1 -> n0 [label="depends on"];
+}
+`
+	if cycle.Graph(DOT) != expected {
+		t.Errorf("Expected the graph\n%s\ngot\n%s", expected, cycle.Graph(DOT))
+	}
+}
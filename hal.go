@@ -0,0 +1,115 @@
+package api
+
+import (
+	"io"
+	"reflect"
+)
+
+// Encodes the responses in HAL, https://datatracker.ietf.org/doc/html/draft-kelly-json-hal
+// The _links of each resource come from the Route tree: self, up, its children and actions,
+// with the IDs caught in the URI. The elements of a list are sent in _embedded
+// The Meta entries are sent as properties and its links are added to the _links
+type HALEncoder struct {
+	Indent string
+}
+
+func (e HALEncoder) MediaType() string {
+	return "application/hal+json"
+}
+
+func (e HALEncoder) Encode(w io.Writer, v interface{}) error {
+	return writeJSON(w, reflect.ValueOf(v), "", e.Indent)
+}
+
+func (e HALEncoder) streams() {}
+
+func (e HALEncoder) document(v interface{}, r *reply) interface{} {
+
+	meta, metaLinks := r.Meta.snapshot()
+	links := halLinks(r.Route.links(r.IDs))
+	for i, rel := range metaLinks.Names {
+		links.set(rel, halLink(routeLink{Href: metaLinks.Values[i].(string)}))
+	}
+
+	body := outputMap{}
+
+	if outputs, ok := v.(outputMap); ok {
+		body = outputs.clone()
+	} else {
+		value := reflect.ValueOf(v)
+
+		embedded := []interface{}{}
+		isList, _ := eachElem(value, func(elem reflect.Value) error {
+			object := halObject(elem)
+			if elemLinks, ok := r.Route.elemLinks(elem, r.IDs); ok {
+				object.set("_links", halLinks(elemLinks))
+			}
+			embedded = append(embedded, object)
+			return nil
+		})
+
+		if isList {
+			body.add("_embedded", outputMap{Names: []string{r.Route.Name}, Values: []interface{}{embedded}})
+		} else {
+			body = halObject(value)
+		}
+	}
+
+	for i, name := range meta.Names {
+		body.set(name, meta.Values[i])
+	}
+
+	body.insert(0, "_links", links)
+	return body
+}
+
+func (e HALEncoder) errorDocument(err error, status int, r *reply) interface{} {
+	body := outputMap{}
+	for k, v := range errorBody(err) {
+		body.add(k, v)
+	}
+	body.insert(0, "_links", halLinks([]routeLink{{Rel: "self", Href: r.Request.URL.Path}}))
+	return body
+}
+
+// Return the properties of the resource, like JSON encodes them
+// Values that aren't Structs are sent in the value property
+func halObject(v reflect.Value) outputMap {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return outputMap{}
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || isEncodedByItself(v.Type()) {
+		return outputMap{Names: []string{"value"}, Values: []interface{}{v.Interface()}}
+	}
+
+	object := outputMap{}
+	for _, f := range jsonFields(v.Type()) {
+		fv := fieldValue(v, f)
+		if !fv.IsValid() || f.OmitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		object.add(f.Name, fv.Interface())
+	}
+	return object
+}
+
+// Return the links as HAL link objects, by its relation
+func halLinks(links []routeLink) outputMap {
+	m := outputMap{}
+	for _, l := range links {
+		m.add(l.Rel, halLink(l))
+	}
+	return m
+}
+
+func halLink(l routeLink) outputMap {
+	link := outputMap{Names: []string{"href"}, Values: []interface{}{l.Href}}
+	if l.Templated {
+		link.add("templated", true)
+	}
+	return link
+}
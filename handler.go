@@ -11,6 +11,24 @@ type handler struct {
 	// It could occour couse could have any number of Interfaces
 	// that could be satisfied by a single dependency
 	Dependencies dependencies
+
+	// The Route this handler answers for
+	Route *Route
+
+	// True if the handler or some Init method asks for error or []error
+	// If nobody asks for them, a failing Init should stop the chain
+	ConsumesErrors bool
+
+	// The order to construct the dependencies
+	Plan *plan
+
+	// What the handler is for, from the Routes declared by its Resource
+	Doc Doc
+
+	// The description of the handler and the name of its method,
+	// computed once its path is known, so the requests don't build them
+	Endpoint Endpoint
+	Name     string
 }
 
 func newHandler(m reflect.Method, r *Resource) (*handler, error) {
@@ -19,6 +37,12 @@ func newHandler(m reflect.Method, r *Resource) (*handler, error) {
 
 	met := newMethod(m)
 
+	// Each output should have its own name in the response
+	err := met.checkOutputNames()
+	if err != nil {
+		return nil, err
+	}
+
 	h := &handler{
 		Method:       met,
 		Dependencies: make(map[reflect.Type]*dependency),
@@ -27,12 +51,14 @@ func newHandler(m reflect.Method, r *Resource) (*handler, error) {
 	// So we scan all dependencies to create a tree
 	for _, input := range met.Inputs {
 		// Scan this dependency and its dependencies recursively
-		err := h.newDependency(input, r)
+		err = h.newDependency(input, r)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	h.ConsumesErrors = h.consumesErrors()
+
 	return h, nil
 }
 
@@ -123,6 +149,27 @@ func (h *handler) newInitMethod(d *dependency, r *Resource) error {
 	return nil
 }
 
+// Return true if the handler method or any Init method
+// of its dependencies receives error or []error
+func (h *handler) consumesErrors() bool {
+	methods := []*method{h.Method}
+	for _, d := range h.Dependencies {
+		if d.Method != nil {
+			methods = append(methods, d.Method)
+		}
+	}
+
+	for _, m := range methods {
+		for _, t := range m.Inputs {
+			if t == errorType || t == errorSliceType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (h *handler) String() string {
 	return fmt.Sprintf("Handler: [%s%s] %s", h.Method.HTTPMethod, h.Method.Name, h.Method.Method.Type)
 }
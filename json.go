@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+// Encodes the responses in JSON
+// With an empty Indent the JSON is compact, otherwise it is pretty printed
+// Lists, iterators, channels and the multiple outputs of a handler
+// are written straight in the response, element by element
+type JSONEncoder struct {
+	Indent string
+}
+
+func (e JSONEncoder) MediaType() string {
+	return "application/json"
+}
+
+func (e JSONEncoder) Encode(w io.Writer, v interface{}) error {
+	return writeJSON(w, reflect.ValueOf(v), "", e.Indent)
+}
+
+func (e JSONEncoder) streams() {}
+
+// Write the JSON of the value in the writer
+// Each line starts with the prefix and is indented by the indent, like json.MarshalIndent does
+// Values that are not lists are marshalled at once
+func writeJSON(w io.Writer, v reflect.Value, prefix, indent string) error {
+
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+		_, err := io.WriteString(w, "null")
+		return err
+	}
+
+	if outputs, ok := v.Interface().(outputMap); ok {
+		return writeJSONOutputs(w, outputs, prefix, indent)
+	}
+
+	// The methods with Ptr receivers are only called by encoding/json in Ptrs
+	if isEncodedByItself(v.Type()) {
+		if v.Kind() != reflect.Ptr && v.CanAddr() {
+			v = v.Addr()
+		}
+		return marshalJSON(w, v.Interface(), prefix, indent)
+	}
+
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return marshalJSON(w, nil, prefix, indent)
+	}
+
+	// Values produced in time are sent as soon as they arrive
+	flusher, flush := w.(http.Flusher)
+	flush = flush && isSequence(v.Type())
+
+	written := false
+	isList, err := eachElem(v, func(elem reflect.Value) error {
+		delim := ","
+		if !written {
+			delim = "["
+			written = true
+		}
+		err := writeDelim(w, delim, prefix+indent, indent)
+		if err != nil {
+			return err
+		}
+		err = writeJSON(w, elem, prefix+indent, indent)
+		if flush && err == nil {
+			flusher.Flush()
+		}
+		return err
+	})
+
+	if !isList {
+		return marshalJSON(w, v.Interface(), prefix, indent)
+	}
+	if err != nil {
+		return err
+	}
+	if !written {
+		_, err = io.WriteString(w, "[]")
+		return err
+	}
+	return writeDelim(w, "]", prefix, indent)
+}
+
+// Write the outputs as an object, in the order they were returned
+func writeJSONOutputs(w io.Writer, outputs outputMap, prefix, indent string) error {
+
+	if len(outputs.Names) == 0 {
+		_, err := io.WriteString(w, "{}")
+		return err
+	}
+
+	colon := ":"
+	if len(indent) > 0 {
+		colon = ": "
+	}
+
+	for i, name := range outputs.Names {
+		delim := ","
+		if i == 0 {
+			delim = "{"
+		}
+		err := writeDelim(w, delim, prefix+indent, indent)
+		if err != nil {
+			return err
+		}
+
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(key, colon...))
+		if err != nil {
+			return err
+		}
+
+		err = writeJSON(w, reflect.ValueOf(outputs.Values[i]), prefix+indent, indent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeDelim(w, "}", prefix, indent)
+}
+
+// Write the delimiter followed by a new line, when it is pretty printed
+// Closing delimiters are written after the new line
+func writeDelim(w io.Writer, delim, prefix, indent string) error {
+	text := delim
+	if len(indent) > 0 {
+		switch delim {
+		case "]", "}":
+			text = "\n" + prefix + delim
+		default:
+			text = delim + "\n" + prefix
+		}
+	}
+	_, err := io.WriteString(w, text)
+	return err
+}
+
+// Marshal a single value and write it
+func marshalJSON(w io.Writer, v interface{}, prefix, indent string) error {
+	var data []byte
+	var err error
+
+	if len(indent) > 0 {
+		data, err = json.MarshalIndent(v, prefix, indent)
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Decodes the request bodies in JSON
+type JSONDecoder struct{}
+
+func (d JSONDecoder) MediaType() string {
+	return "application/json"
+}
+
+func (d JSONDecoder) Decode(req *http.Request, v interface{}) error {
+	return json.NewDecoder(req.Body).Decode(v)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type StreamAPI struct {
+	Events EventList
+}
+
+type EventList []Event
+
+type Event struct {
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+}
+
+var streamEvents = []Event{
+	{Name: "start", At: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+	{Name: "stop", At: time.Date(2020, 1, 2, 3, 5, 0, 0, time.UTC)},
+}
+
+func (l *EventList) GET() iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		for _, e := range streamEvents {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+func (l *EventList) GETLive() <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for _, e := range streamEvents {
+			events <- e
+		}
+	}()
+	return events
+}
+
+// Closed when the feed producer sent all its events
+var feedDone chan struct{}
+
+func (l *EventList) GETFeed() <-chan Event {
+	events := make(chan Event)
+	done := feedDone
+	go func() {
+		defer close(done)
+		defer close(events)
+		for i := 0; i < 100; i++ {
+			events <- streamEvents[i%len(streamEvents)]
+		}
+	}()
+	return events
+}
+
+func (l *EventList) GETSummary() (*Event, *Book, error) {
+	return &streamEvents[0], &Book{Title: "Log"}, nil
+}
+
+func TestWriteJSON(t *testing.T) {
+	values := []interface{}{
+		BookList{
+			{Title: "Dom Casmurro", Price: 10.5, Author: &Author{Name: "Machado"}},
+			{Title: "Untitled"},
+		},
+		[]interface{}{1, "a", nil, []int{}, []int(nil), map[string]int{"b": 2, "a": 1}},
+		[][]string{{"a", "b"}, {}},
+		streamEvents,
+		&streamEvents,
+		[]byte("bytes"),
+		[]int{},
+		[]int(nil),
+		"text",
+		nil,
+	}
+
+	for _, v := range values {
+		for _, indent := range []string{"", "\t", "  "} {
+			var expected []byte
+			if len(indent) > 0 {
+				expected, _ = json.MarshalIndent(v, "", indent)
+			} else {
+				expected, _ = json.Marshal(v)
+			}
+
+			buf := &bytes.Buffer{}
+			err := JSONEncoder{Indent: indent}.Encode(buf, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), expected) {
+				t.Errorf("Indent %q: expected\n%s\ngot\n%s", indent, expected, buf)
+			}
+		}
+	}
+}
+
+func TestStreamSequences(t *testing.T) {
+	route := newTestRoute(t, StreamAPI{})
+
+	expected, _ := json.MarshalIndent(streamEvents, "", "\t")
+
+	for _, uri := range []string{"/streamapi/events", "/streamapi/events/live"} {
+		res := serveAccept(t, route, uri, "application/json")
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", uri, http.StatusOK, res.Code, res.Body)
+		}
+		if res.Body.String() != string(expected) {
+			t.Errorf("%s: expected\n%s\ngot\n%s", uri, expected, res.Body)
+		}
+
+		// The elements of a channel are sent as soon as they are written
+		if strings.HasSuffix(uri, "live") && !res.Flushed {
+			t.Errorf("%s: expected the response to be flushed", uri)
+		}
+
+		// Encoders that don't stream receive the elements collected
+		res = serveAccept(t, route, uri, "application/xml")
+		if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "<Name>stop</Name>") {
+			t.Errorf("%s: unexpected XML response %d: %s", uri, res.Code, res.Body)
+		}
+
+		res = serveAccept(t, route, uri, "text/csv")
+		if res.Body.String() != "name,at\nstart,2020-01-02T03:04:05Z\nstop,2020-01-02T03:05:00Z\n" {
+			t.Errorf("%s: unexpected CSV response %d: %s", uri, res.Code, res.Body)
+		}
+
+		res = serveAccept(t, route, uri, "application/x-ndjson")
+		if strings.Count(res.Body.String(), "\n") != 2 {
+			t.Errorf("%s: unexpected NDJSON response %d: %s", uri, res.Code, res.Body)
+		}
+	}
+}
+
+func TestStreamOutputs(t *testing.T) {
+	route := newTestRoute(t, StreamAPI{})
+
+	// The outputs are written in the order they are returned, not sorted
+	res := serveAccept(t, route, "/streamapi/events/summary", "application/json")
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, res.Code, res.Body)
+	}
+
+	expected := `{"event":{"name":"start","at":"2020-01-02T03:04:05Z"},"book":{"title":"Log","price":0}}`
+	compact := &bytes.Buffer{}
+	json.Compact(compact, res.Body.Bytes())
+	if compact.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, compact)
+	}
+
+	res = serveAccept(t, route, "/streamapi/events/summary", "application/yaml")
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"Log"`) {
+		t.Errorf("Unexpected YAML response %d: %s", res.Code, res.Body)
+	}
+}
+
+// A client that goes away after the first write
+type disconnectingWriter struct {
+	header http.Header
+	writes int
+}
+
+func (w *disconnectingWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *disconnectingWriter) WriteHeader(status int) {}
+
+func (w *disconnectingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes > 1 {
+		return 0, errors.New("client disconnected")
+	}
+	return len(b), nil
+}
+
+func TestStreamDisconnect(t *testing.T) {
+	route := newTestRoute(t, StreamAPI{})
+	feedDone = make(chan struct{})
+
+	req, err := http.NewRequest("GET", "/streamapi/events/feed", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+	route.ServeHTTP(&disconnectingWriter{header: http.Header{}}, req)
+
+	// The producer should not be blocked sending the events nobody reads
+	select {
+	case <-feedDone:
+	case <-time.After(time.Second):
+		t.Error("The channel producer is still blocked after the client disconnected")
+	}
+}
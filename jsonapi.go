@@ -0,0 +1,319 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Encodes the responses as JSON:API documents, https://jsonapi.org
+// The type of the resources is the name of its Route, the id is the field tagged api:"id",
+// and the relationships are the fields that are children Routes in the tree
+// Related resources without an id field can't be linked, they are sent in the attributes
+// The multiple outputs of a handler are sent in the meta of the document
+type JSONAPIEncoder struct {
+	Indent string
+}
+
+func (e JSONAPIEncoder) MediaType() string {
+	return "application/vnd.api+json"
+}
+
+func (e JSONAPIEncoder) Encode(w io.Writer, v interface{}) error {
+	return writeJSON(w, reflect.ValueOf(v), "", e.Indent)
+}
+
+func (e JSONAPIEncoder) streams() {}
+
+// Encoders that build the whole document from the Route that answers the request,
+// instead of being wrapped in the envelope
+type documentEncoder interface {
+	Encoder
+	document(v interface{}, r *reply) interface{}
+	errorDocument(err error, status int, r *reply) interface{}
+}
+
+// The resources already added to the document, by its type and id
+type jsonapiDocument struct {
+	Seen     map[string]bool
+	Included []interface{}
+}
+
+func (e JSONAPIEncoder) document(v interface{}, r *reply) interface{} {
+
+	doc := &jsonapiDocument{Seen: map[string]bool{}}
+	body := outputMap{}
+	meta, links := r.Meta.snapshot()
+
+	path := r.Request.URL.Path
+	links.set("self", path)
+
+	if outputs, ok := v.(outputMap); ok {
+		// Not a resource, so it is only meta information
+		for i, name := range outputs.Names {
+			meta.set(name, outputs.Values[i])
+		}
+	} else {
+		value := reflect.ValueOf(v)
+		route := r.Route
+		if route.IsSlice && route.Elem != nil {
+			route = route.Elem
+		}
+
+		data := []interface{}{}
+		isList, _ := eachElem(value, func(elem reflect.Value) error {
+			data = append(data, doc.resource(elem, route, path, true))
+			return nil
+		})
+
+		if isList {
+			body.add("data", data)
+		} else if object := doc.resource(value, route, path, false); object != nil {
+			// The resource answered by its ID in the URI doesn't need the id field
+			if _, ok := object.get("id"); !ok {
+				if id, exist := r.IDs[ptrOfType(route.Value.Type())]; exist {
+					object.insert(1, "id", id.Interface().(*ID).String())
+				}
+			}
+			body.add("data", *object)
+		} else {
+			body.add("data", nil)
+		}
+	}
+
+	if len(doc.Included) > 0 {
+		body.add("included", doc.Included)
+	}
+	if len(meta.Names) > 0 {
+		body.add("meta", meta)
+	}
+	body.add("links", links)
+
+	return body
+}
+
+func (e JSONAPIEncoder) errorDocument(err error, status int, r *reply) interface{} {
+	object := outputMap{}
+	object.add("status", strconv.Itoa(status))
+	if e, ok := asHTTPError(err); ok && len(e.Code) > 0 {
+		object.add("code", e.Code)
+	}
+	object.add("title", err.Error())
+
+	body := outputMap{}
+	body.add("errors", []interface{}{object})
+
+	meta, _ := r.Meta.snapshot()
+	if len(meta.Names) > 0 {
+		body.add("meta", meta)
+	}
+	return body
+}
+
+// Return the resource object of the value, answered by the given Route
+// The path is the URI of the value, or of its list when inList is true
+// Its related resources are added to the included of the document
+func (doc *jsonapiDocument) resource(v reflect.Value, ro *Route, path string, inList bool) *outputMap {
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	object := &outputMap{}
+	object.add("type", ro.Name)
+
+	id, hasID := resourceID(v)
+	if hasID {
+		object.add("id", id)
+		if inList {
+			path += "/" + id
+		}
+	}
+
+	attributes := outputMap{}
+	relationships := outputMap{}
+
+	t := v.Type()
+	for _, f := range jsonFields(t) {
+		if hasAPIOption(t.FieldByIndex(f.Index).Tag, "id") {
+			continue
+		}
+
+		fv := fieldValue(v, f)
+		if !fv.IsValid() || f.OmitEmpty && isEmptyValue(fv) {
+			continue
+		}
+
+		child := ro.childOfField(t.FieldByIndex(f.Index))
+		if child != nil && hasIDField(child) {
+			relationships.add(f.Name, doc.relationship(fv, child, path+"/"+child.Name))
+			continue
+		}
+
+		attributes.add(f.Name, fv.Interface())
+	}
+
+	if len(attributes.Names) > 0 {
+		object.add("attributes", attributes)
+	}
+	if len(relationships.Names) > 0 {
+		object.add("relationships", relationships)
+	}
+	if hasID || !inList {
+		object.add("links", outputMap{Names: []string{"self"}, Values: []interface{}{path}})
+	}
+
+	return object
+}
+
+// Return the relationship object linking the related resources
+// The resources are added to the included, once
+func (doc *jsonapiDocument) relationship(v reflect.Value, ro *Route, path string) outputMap {
+
+	elemRoute := ro
+	if ro.IsSlice && ro.Elem != nil {
+		elemRoute = ro.Elem
+	}
+
+	identifier := func(elem reflect.Value) interface{} {
+		object := doc.resource(elem, elemRoute, path, ro.IsSlice)
+		if object == nil {
+			return nil
+		}
+
+		id, _ := object.get("id")
+		key := ro.Name + "/" + fmt.Sprint(id)
+		if !doc.Seen[key] {
+			doc.Seen[key] = true
+			doc.Included = append(doc.Included, *object)
+		}
+
+		return outputMap{Names: []string{"type", "id"}, Values: []interface{}{ro.Name, id}}
+	}
+
+	var data interface{}
+	if ro.IsSlice {
+		list := []interface{}{}
+		eachElem(v, func(elem reflect.Value) error {
+			if i := identifier(elem); i != nil {
+				list = append(list, i)
+			}
+			return nil
+		})
+		data = list
+	} else {
+		data = identifier(v)
+	}
+
+	relationship := outputMap{}
+	relationship.add("data", data)
+	relationship.add("links", outputMap{Names: []string{"related"}, Values: []interface{}{path}})
+	return relationship
+}
+
+// Return the Route of the child resource created by the Struct field
+func (ro *Route) childOfField(field reflect.StructField) *Route {
+	if isIgnoredField(field) {
+		return nil
+	}
+	return ro.Children[resourceName(field)]
+}
+
+// Return true if the resources of the Route can be identified
+func hasIDField(ro *Route) bool {
+	t := elemOfType(ro.Value.Type())
+	if ro.IsSlice && ro.Elem != nil {
+		t = elemOfType(ro.Elem.Value.Type())
+	}
+	_, ok := idFieldOf(t)
+	return ok
+}
+
+// Return the id of the resource, from the field tagged api:"id"
+func resourceID(v reflect.Value) (string, bool) {
+	f, ok := idFieldOf(v.Type())
+	if !ok {
+		return "", false
+	}
+	fv := fieldValue(v, f)
+	if !fv.IsValid() {
+		return "", false
+	}
+	return fmt.Sprint(fv.Interface()), true
+}
+
+// Return the field tagged api:"id" of the Struct Type
+func idFieldOf(t reflect.Type) (jsonField, bool) {
+	if t.Kind() != reflect.Struct {
+		return jsonField{}, false
+	}
+	for _, f := range jsonFields(t) {
+		if hasAPIOption(t.FieldByIndex(f.Index).Tag, "id") {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// Return true if the api tag has the option, like api:"id"
+func hasAPIOption(tag reflect.StructTag, option string) bool {
+	for _, opt := range strings.Split(tag.Get("api"), ",") {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Decodes the request bodies sent as JSON:API documents
+// The attributes are read like JSON and the id is set in the field tagged api:"id"
+type JSONAPIDecoder struct{}
+
+func (d JSONAPIDecoder) MediaType() string {
+	return "application/vnd.api+json"
+}
+
+func (d JSONAPIDecoder) Decode(req *http.Request, v interface{}) error {
+	doc := struct {
+		Data *struct {
+			ID         string          `json:"id"`
+			Attributes json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}{}
+
+	err := json.NewDecoder(req.Body).Decode(&doc)
+	if err != nil {
+		return err
+	}
+	if doc.Data == nil {
+		return nil
+	}
+
+	if len(doc.Data.Attributes) > 0 {
+		err = json.Unmarshal(doc.Data.Attributes, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	f, ok := idFieldOf(value.Type())
+	if ok && len(doc.Data.ID) > 0 {
+		return setString(settableField(value, f), []string{doc.Data.ID})
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type LibraryAPI struct {
+	Shelves ShelfList
+}
+
+type ShelfList []Shelf
+
+type Shelf struct {
+	ID      int        `json:"id" api:"id"`
+	Label   string     `json:"label"`
+	Volumes VolumeList `json:"volumes"`
+	Author  *Author    `json:"author,omitempty"`
+}
+
+type VolumeList []Volume
+
+type Volume struct {
+	Code  string `json:"code" api:"id"`
+	Title string `json:"title"`
+}
+
+func (l *ShelfList) GET() ShelfList {
+	return ShelfList{
+		{ID: 1, Label: "Novels", Volumes: VolumeList{{Code: "v1", Title: "Dom Casmurro"}}},
+		{ID: 2, Label: "Poems", Volumes: VolumeList{{Code: "v1", Title: "Dom Casmurro"}, {Code: "v2", Title: "Lira"}}},
+	}
+}
+
+func (s *Shelf) GET(id *ID, meta *Meta) *Shelf {
+	meta.Set("cached", true)
+	s.ID, _ = id.Int()
+	s.Label = "Novels"
+	s.Author = &Author{Name: "Machado"}
+	return s
+}
+
+func (s *Shelf) PUT() *Shelf {
+	return s
+}
+
+func (l *VolumeList) GET() VolumeList {
+	return *l
+}
+
+func serveJSONAPI(t *testing.T, route *Route, method, uri, body string) (int, string) {
+	res := httptest.NewRecorder()
+	req, err := http.NewRequest(method, uri, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/vnd.api+json")
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+	route.ServeHTTP(res, req)
+
+	if res.Header().Get("Content-Type") != "application/vnd.api+json" {
+		t.Errorf("%s: unexpected Content-Type %s", uri, res.Header().Get("Content-Type"))
+	}
+	compact := &bytes.Buffer{}
+	json.Compact(compact, res.Body.Bytes())
+	return res.Code, compact.String()
+}
+
+func TestJSONAPI(t *testing.T) {
+	route := newTestRoute(t, LibraryAPI{})
+
+	tests := []struct {
+		Method   string
+		URI      string
+		Body     string
+		Status   int
+		Expected string
+	}{
+		{"GET", "/libraryapi/shelves", "", http.StatusOK, `{"data":[` +
+			`{"type":"shelves","id":"1","attributes":{"label":"Novels"},"relationships":{"volumes":{` +
+			`"data":[{"type":"volumes","id":"v1"}],"links":{"related":"/libraryapi/shelves/1/volumes"}}},` +
+			`"links":{"self":"/libraryapi/shelves/1"}},` +
+			`{"type":"shelves","id":"2","attributes":{"label":"Poems"},"relationships":{"volumes":{` +
+			`"data":[{"type":"volumes","id":"v1"},{"type":"volumes","id":"v2"}],"links":{"related":"/libraryapi/shelves/2/volumes"}}},` +
+			`"links":{"self":"/libraryapi/shelves/2"}}],` +
+			`"included":[` +
+			`{"type":"volumes","id":"v1","attributes":{"title":"Dom Casmurro"},"links":{"self":"/libraryapi/shelves/1/volumes/v1"}},` +
+			`{"type":"volumes","id":"v2","attributes":{"title":"Lira"},"links":{"self":"/libraryapi/shelves/2/volumes/v2"}}],` +
+			`"links":{"self":"/libraryapi/shelves"}}`},
+		// Related resources without id are attributes
+		{"GET", "/libraryapi/shelves/3", "", http.StatusOK, `{"data":` +
+			`{"type":"shelves","id":"3","attributes":{"label":"Novels","author":{"name":"Machado"}},` +
+			`"relationships":{"volumes":{"data":[],"links":{"related":"/libraryapi/shelves/3/volumes"}}},` +
+			`"links":{"self":"/libraryapi/shelves/3"}},` +
+			`"meta":{"cached":true},"links":{"self":"/libraryapi/shelves/3"}}`},
+		{"PUT", "/libraryapi/shelves/3", `{"data":{"type":"shelves","id":"3","attributes":{"label":"Essays"}}}`, http.StatusOK,
+			`{"data":{"type":"shelves","id":"3","attributes":{"label":"Essays"},` +
+				`"relationships":{"volumes":{"data":[],"links":{"related":"/libraryapi/shelves/3/volumes"}}},` +
+				`"links":{"self":"/libraryapi/shelves/3"}},` +
+				`"links":{"self":"/libraryapi/shelves/3"}}`},
+		{"GET", "/libraryapi/nothing", "", http.StatusNotFound, `{"errors":[{"status":"404",` +
+			`"title":"Not found any handler for [GET] /libraryapi/nothing in the Route: [libraryapi] *api.LibraryAPI"}]}`},
+	}
+
+	for _, test := range tests {
+		status, body := serveJSONAPI(t, route, test.Method, test.URI, test.Body)
+		if status != test.Status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", test.Method, test.URI, test.Status, status, body)
+			continue
+		}
+		if body != test.Expected {
+			t.Errorf("%s %s: expected\n%s\ngot\n%s", test.Method, test.URI, test.Expected, body)
+		}
+	}
+}
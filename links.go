@@ -0,0 +1,203 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A link to a resource, found in the Route tree
+// Templated links have an {id} to be filled by the client
+type routeLink struct {
+	Rel       string
+	Href      string
+	Templated bool
+}
+
+// The placeholder of the IDs not caught in the URI
+const idTemplate = "{id}"
+
+// Return the path of the Route from the root, with the IDs caught in the URI
+// The IDs not caught are written as {id}, and the path is a template
+func (ro *Route) path(ids idMap) (string, bool) {
+	segments := []string{}
+	templated := false
+
+	for r := ro; r != nil; r = r.Parent {
+		// The Elem of a list is found by its ID
+		if r.Parent != nil && r.Parent.Elem == r {
+			id, exist := ids[ptrOfType(r.Value.Type())]
+			if exist && !id.IsNil() {
+				segments = append(segments, id.Interface().(*ID).String())
+			} else {
+				segments = append(segments, idTemplate)
+				templated = true
+			}
+			continue
+		}
+		segments = append(segments, r.Name)
+	}
+
+	// They were found from the Route to the root
+	for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+		segments[i], segments[j] = segments[j], segments[i]
+	}
+
+	return "/" + strings.Join(segments, "/"), templated
+}
+
+// Return the links of the resource answered by the Route:
+// self, up, its children, its actions and the item of a list
+func (ro *Route) links(ids idMap) []routeLink {
+
+	self, templated := ro.path(ids)
+	links := []routeLink{{Rel: "self", Href: self, Templated: templated}}
+
+	if ro.Parent != nil {
+		up, templated := ro.Parent.path(ids)
+		links = append(links, routeLink{Rel: "up", Href: up, Templated: templated})
+	}
+
+	if ro.IsSlice && ro.Elem != nil {
+		links = append(links, routeLink{Rel: "item", Href: self + "/" + idTemplate, Templated: true})
+	}
+
+	names := []string{}
+	for name := range ro.Children {
+		names = append(names, name)
+	}
+	for _, h := range ro.Handlers {
+		if len(h.Method.Name) > 0 && !containsString(names, h.Method.Name) {
+			names = append(names, h.Method.Name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		links = append(links, routeLink{Rel: name, Href: self + "/" + name, Templated: templated})
+	}
+
+	return links
+}
+
+// Return the links of an element of the list answered by the Route
+// Only the elements with a field tagged api:"id" can be linked
+func (ro *Route) elemLinks(v reflect.Value, ids idMap) ([]routeLink, bool) {
+	if !ro.IsSlice || ro.Elem == nil {
+		return nil, false
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	id, ok := resourceID(v)
+	if !ok {
+		return nil, false
+	}
+
+	elemIDs := idMap{}
+	elemIDs.extend(ids)
+	elemIDs[ptrOfType(ro.Elem.Value.Type())] = reflect.ValueOf(&ID{id: id})
+
+	return ro.Elem.links(elemIDs), true
+}
+
+// Return the URL of the resource of the given Type, found in this Route tree
+// The Type can be given by a reflect.Type or by a value of it, like Item{} or &Item{}
+// Each list in the way to the resource needs an ID, given in order from the root
+// If the value has a field tagged api:"id", it is the ID of the resource itself
+// Ex:
+//
+//	route.URLFor(reflect.TypeOf(B{}), 3, 7) // /api/as/3/bs/7
+func (ro *Route) URLFor(v interface{}, ids ...interface{}) (string, error) {
+
+	t, ok := v.(reflect.Type)
+	if !ok {
+		value := reflect.ValueOf(v)
+		if !value.IsValid() {
+			return "", fmt.Errorf("The URL of a nil value can't be found in the %s", ro)
+		}
+		t = value.Type()
+
+		// The value knows its own ID
+		for value.Kind() == reflect.Ptr && !value.IsNil() {
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Struct {
+			if id, ok := resourceID(value); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	target := ro.routeOfType(ptrOfType(t))
+	if target == nil {
+		return "", fmt.Errorf("The type %s has no Route in the %s", t, ro)
+	}
+
+	// The lists in the way, from the root
+	elems := []*Route{}
+	for r := target; r != nil; r = r.Parent {
+		if r.Parent != nil && r.Parent.Elem == r {
+			elems = append([]*Route{r}, elems...)
+		}
+	}
+	if len(ids) != len(elems) {
+		return "", fmt.Errorf("The URL of %s needs %d IDs, %d given", t, len(elems), len(ids))
+	}
+
+	idsOf := idMap{}
+	for i, r := range elems {
+		idsOf[ptrOfType(r.Value.Type())] = reflect.ValueOf(&ID{id: url.PathEscape(fmt.Sprint(ids[i]))})
+	}
+
+	path, _ := target.path(idsOf)
+	return path, nil
+}
+
+// Return the first Route of the Type, looking level by level
+// The children of each level are looked in the order of its names
+func (ro *Route) routeOfType(t reflect.Type) *Route {
+	level := []*Route{ro}
+
+	for len(level) > 0 {
+		next := []*Route{}
+		for _, r := range level {
+			if ptrOfType(r.Value.Type()) == t {
+				return r
+			}
+			if r.Elem != nil {
+				next = append(next, r.Elem)
+			}
+			names := []string{}
+			for name := range r.Children {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				next = append(next, r.Children[name])
+			}
+		}
+		level = next
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, each := range list {
+		if each == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRouteLinks(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+	route.Options = &Options{Envelope: &Envelope{}, Links: true}
+
+	tests := []struct {
+		URI   string
+		Links string
+	}{
+		{"/routerapi/items", `{"self":"/routerapi/items","up":"/routerapi",` +
+			`"item":"/routerapi/items/{id}","login":"/routerapi/items/login"}`},
+		{"/routerapi/items/login", `{"self":"/routerapi/items","up":"/routerapi",` +
+			`"item":"/routerapi/items/{id}","login":"/routerapi/items/login"}`},
+		{"/routerapi/items/7", `{"self":"/routerapi/items/7","up":"/routerapi/items",` +
+			`"parts":"/routerapi/items/7/parts"}`},
+		{"/routerapi/items/7/parts/3", `{"self":"/routerapi/items/7/parts/3","up":"/routerapi/items/7/parts"}`},
+		{"/routerapi/profile", `{"self":"/routerapi/profile","up":"/routerapi",` +
+			`"login":"/routerapi/profile/login","settings":"/routerapi/profile/settings"}`},
+	}
+
+	for _, test := range tests {
+		res := serve(t, route, "GET", test.URI)
+
+		body := struct {
+			Links json.RawMessage `json:"links"`
+		}{}
+		err := json.Unmarshal(res.Body.Bytes(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		links := &bytes.Buffer{}
+		json.Compact(links, body.Links)
+		if links.String() != test.Links {
+			t.Errorf("%s: expected links\n%s\ngot\n%s", test.URI, test.Links, links)
+		}
+	}
+}
+
+func TestHAL(t *testing.T) {
+	route := newTestRoute(t, LibraryAPI{})
+
+	tests := []struct {
+		URI      string
+		Expected string
+	}{
+		{"/libraryapi/shelves", `{"_links":{"self":{"href":"/libraryapi/shelves"},"up":{"href":"/libraryapi"},` +
+			`"item":{"href":"/libraryapi/shelves/{id}","templated":true}},` +
+			`"_embedded":{"shelves":[` +
+			`{"id":1,"label":"Novels","volumes":[{"code":"v1","title":"Dom Casmurro"}],"_links":{` +
+			`"self":{"href":"/libraryapi/shelves/1"},"up":{"href":"/libraryapi/shelves"},"volumes":{"href":"/libraryapi/shelves/1/volumes"}}},` +
+			`{"id":2,"label":"Poems","volumes":[{"code":"v1","title":"Dom Casmurro"},{"code":"v2","title":"Lira"}],"_links":{` +
+			`"self":{"href":"/libraryapi/shelves/2"},"up":{"href":"/libraryapi/shelves"},"volumes":{"href":"/libraryapi/shelves/2/volumes"}}}]}}`},
+		{"/libraryapi/shelves/3", `{"_links":{"self":{"href":"/libraryapi/shelves/3"},"up":{"href":"/libraryapi/shelves"},` +
+			`"volumes":{"href":"/libraryapi/shelves/3/volumes"}},` +
+			`"id":3,"label":"Novels","volumes":null,"author":{"name":"Machado"},"cached":true}`},
+		{"/libraryapi/nothing", `{"_links":{"self":{"href":"/libraryapi/nothing"}},` +
+			`"error":"Not found any handler for [GET] /libraryapi/nothing in the Route: [libraryapi] *api.LibraryAPI"}`},
+	}
+
+	for _, test := range tests {
+		res := serveAccept(t, route, test.URI, "application/hal+json")
+		if res.Header().Get("Content-Type") != "application/hal+json" {
+			t.Errorf("%s: unexpected Content-Type %s", test.URI, res.Header().Get("Content-Type"))
+		}
+
+		body := &bytes.Buffer{}
+		json.Compact(body, res.Body.Bytes())
+		if body.String() != test.Expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", test.URI, test.Expected, body)
+		}
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+
+	tests := []struct {
+		Value    interface{}
+		IDs      []interface{}
+		Expected string
+	}{
+		{reflect.TypeOf(RouterAPI{}), nil, "/routerapi"},
+		{reflect.TypeOf(ItemList{}), nil, "/routerapi/items"},
+		{reflect.TypeOf(Item{}), []interface{}{7}, "/routerapi/items/7"},
+		{&Item{}, []interface{}{"a b"}, "/routerapi/items/a%20b"},
+		{Part{}, []interface{}{7, 3}, "/routerapi/items/7/parts/3"},
+		{PartList{}, []interface{}{7}, "/routerapi/items/7/parts"},
+		{Profile{}, nil, "/routerapi/profile"},
+	}
+
+	for _, test := range tests {
+		url, err := route.URLFor(test.Value, test.IDs...)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if url != test.Expected {
+			t.Errorf("Expected the URL %s, got %s", test.Expected, url)
+		}
+	}
+
+	_, err := route.URLFor(Part{}, 7)
+	if err == nil || err.Error() != "The URL of api.Part needs 2 IDs, 1 given" {
+		t.Errorf("Expected an error for missing IDs, got %v", err)
+	}
+
+	_, err = route.URLFor(Book{})
+	if err == nil {
+		t.Error("Expected an error for a type out of the Route tree")
+	}
+
+	// The ID of the value itself
+	library := newTestRoute(t, LibraryAPI{})
+	url, err := library.URLFor(&Shelf{ID: 4})
+	if err != nil || url != "/libraryapi/shelves/4" {
+		t.Errorf("Expected the URL of the shelf, got %s %v", url, err)
+	}
+}
+
+type WarehouseAPI struct {
+	Crates CrateList
+}
+
+type CrateList []Crate
+
+func (l *CrateList) POST(meta *Meta) *Crate {
+	meta.Location(9)
+	return &Crate{}
+}
+
+type Crate struct {
+	Labels LabelList
+}
+
+func (c *Crate) GET() {}
+
+type LabelList []Label
+
+func (l *LabelList) POST(meta *Meta) {
+	meta.Location("fragile glass")
+}
+
+type Label struct{}
+
+func (l *Label) GET() {}
+
+func TestLocation(t *testing.T) {
+	route := newTestRoute(t, WarehouseAPI{})
+
+	tests := []struct {
+		Method   string
+		URI      string
+		Status   int
+		Location string
+	}{
+		{"POST", "/warehouseapi/crates", http.StatusCreated, "/warehouseapi/crates/9"},
+		{"POST", "/warehouseapi/crates/5/labels", http.StatusCreated, "/warehouseapi/crates/5/labels/fragile%20glass"},
+		{"GET", "/warehouseapi/crates/5", http.StatusNoContent, ""},
+	}
+
+	for _, test := range tests {
+		res := serve(t, route, test.Method, test.URI)
+		if res.Code != test.Status {
+			t.Errorf("%s %s: expected the status %d, got %d", test.Method, test.URI, test.Status, res.Code)
+		}
+		if res.Header().Get("Location") != test.Location {
+			t.Errorf("%s %s: expected the Location %q, got %q", test.Method, test.URI, test.Location, res.Header().Get("Location"))
+		}
+	}
+}
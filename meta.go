@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+)
+
+// The metadata sent in the envelope of the response, like counts, cursors and warnings
+// Handlers and Init methods receive it asking for *api.Meta, like http.ResponseWriter
+// It can be used by the Init methods constructed at the same time
+type Meta struct {
+	mutex   sync.Mutex
+	entries outputMap
+	links   outputMap
+
+	// The ID of the element created by the request, sent in the Location header
+	location *ID
+}
+
+var (
+	metaPtrType = reflect.TypeOf((*Meta)(nil))
+	metaType    = metaPtrType.Elem()
+)
+
+// Set the entry, replacing the one with the same key
+func (m *Meta) Set(key string, v interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries.set(key, v)
+}
+
+// Return the entry with the given key
+func (m *Meta) Get(key string) (interface{}, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.entries.get(key)
+}
+
+// Add a message to the warnings entry
+func (m *Meta) Warn(message string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	warnings, _ := m.entries.get("warnings")
+	list, _ := warnings.([]string)
+	m.entries.set("warnings", append(list, message))
+}
+
+// Set the link sent in the links of the envelope, like next or prev
+func (m *Meta) Link(rel, href string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.links.set(rel, href)
+}
+
+// Answer the request with 201 Created and the Location of the element created with the given ID
+// It is used by the POST handlers of lists, the URL of the element comes from the Route tree
+// Ex:
+//
+//	func (l *ItemList) POST(item Item, meta *api.Meta) Item {
+//		meta.Location(item.ID)
+//		return item
+//	}
+func (m *Meta) Location(id interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.location = &ID{id: url.PathEscape(fmt.Sprint(id))}
+}
+
+// Return the ID set by Location, or nil
+func (m *Meta) created() *ID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.location
+}
+
+// Return a copy of the entries and links, in the order they were set
+func (m *Meta) snapshot() (entries, links outputMap) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.entries.clone(), m.links.clone()
+}
@@ -19,6 +19,9 @@ type method struct {
 	NumOut     int
 	Outputs    []reflect.Type
 	OutName    []string
+
+	// The fields sent as named outputs, if the method returns an api.Response
+	Response []jsonField
 }
 
 var httpMethods = [...]string{
@@ -74,6 +77,10 @@ func newMethod(m reflect.Method) *method {
 		met.OutName[i] = strings.ToLower(t.Name())
 	}
 
+	if met.NumOut == 1 {
+		met.Response = responseFields(met.Outputs[0])
+	}
+
 	return met
 }
 
@@ -81,14 +88,25 @@ func newMethod(m reflect.Method) *method {
 // Main methods respond to directly to the:
 // GET, PUT, POST, DELETE, HEAD of the resources
 // Action methods respond for some action of the resource,
-// ex: LoginGET, respond to: [GET] resource/login
+// ex: GETLogin, respond to: [GET] resource/login
+// The action name follows the Naming strategy, GETLoginHistory could be login-history
+// With StrictMethodNames the action is separated by an underscore: GET_Login
 func decodeMethodName(m reflect.Method) (httpMethod string, name string) {
 
 	for _, httpMethod := range httpMethods {
-		if strings.HasPrefix(m.Name, httpMethod) {
-			name = strings.TrimPrefix(m.Name, httpMethod)
-			return httpMethod, strings.ToLower(name)
+		if !strings.HasPrefix(m.Name, httpMethod) {
+			continue
 		}
+		name = strings.TrimPrefix(m.Name, httpMethod)
+
+		if StrictMethodNames && len(name) > 0 {
+			if !strings.HasPrefix(name, "_") || len(name) == 1 {
+				return "", ""
+			}
+			name = name[1:]
+		}
+
+		return httpMethod, Naming(name)
 	}
 
 	return "", ""
@@ -96,15 +114,10 @@ func decodeMethodName(m reflect.Method) (httpMethod string, name string) {
 
 // Return if this method should be mapped or not
 // Methods starting with GET, POST, PUT, DELETE or HEAD should be mapped
+// With StrictMethodNames, only GET or GET_Action and so on
 func isMappedMethod(m reflect.Method) bool {
-
-	for _, httpMethod := range httpMethods {
-		if strings.HasPrefix(m.Name, httpMethod) {
-			return true
-		}
-	}
-
-	return false
+	httpMethod, _ := decodeMethodName(m)
+	return len(httpMethod) > 0
 }
 
 func (m *method) String() string {
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// Encodes the responses in MessagePack
+// Structs follow the same rules of JSON, its json tags and omitempty
+type MsgPackEncoder struct{}
+
+func (e MsgPackEncoder) MediaType() string {
+	return "application/msgpack"
+}
+
+func (e MsgPackEncoder) Encode(w io.Writer, v interface{}) error {
+	buf := &msgpackWriter{}
+	err := encodeBinary(buf, reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Buffer.Bytes())
+	return err
+}
+
+// Decodes the request bodies in MessagePack
+// Extension types are not supported
+type MsgPackDecoder struct{}
+
+func (d MsgPackDecoder) MediaType() string {
+	return "application/msgpack"
+}
+
+func (d MsgPackDecoder) Decode(req *http.Request, v interface{}) error {
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return decodeBinary(&msgpackReader{Reader: bytes.NewReader(data)}, v)
+}
+
+// Writes the MessagePack values always using its smallest form
+type msgpackWriter struct {
+	Buffer bytes.Buffer
+}
+
+func (w *msgpackWriter) Nil() {
+	w.Buffer.WriteByte(0xc0)
+}
+
+func (w *msgpackWriter) Bool(b bool) {
+	if b {
+		w.Buffer.WriteByte(0xc3)
+	} else {
+		w.Buffer.WriteByte(0xc2)
+	}
+}
+
+func (w *msgpackWriter) Int(i int64) {
+	switch {
+	case i >= 0:
+		w.Uint(uint64(i))
+	case i >= -32:
+		w.Buffer.WriteByte(byte(i)) // negative fixint
+	case i >= math.MinInt8:
+		w.Buffer.WriteByte(0xd0)
+		w.Buffer.WriteByte(byte(i))
+	case i >= math.MinInt16:
+		w.header(0xd1, 2, uint64(i))
+	case i >= math.MinInt32:
+		w.header(0xd2, 4, uint64(i))
+	default:
+		w.header(0xd3, 8, uint64(i))
+	}
+}
+
+func (w *msgpackWriter) Uint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		w.Buffer.WriteByte(byte(u)) // positive fixint
+	case u <= math.MaxUint8:
+		w.header(0xcc, 1, u)
+	case u <= math.MaxUint16:
+		w.header(0xcd, 2, u)
+	case u <= math.MaxUint32:
+		w.header(0xce, 4, u)
+	default:
+		w.header(0xcf, 8, u)
+	}
+}
+
+func (w *msgpackWriter) Float32(f float32) {
+	w.header(0xca, 4, uint64(math.Float32bits(f)))
+}
+
+func (w *msgpackWriter) Float64(f float64) {
+	w.header(0xcb, 8, math.Float64bits(f))
+}
+
+func (w *msgpackWriter) String(s string) {
+	n := uint64(len(s))
+	switch {
+	case n < 32:
+		w.Buffer.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		w.header(0xd9, 1, n)
+	case n <= math.MaxUint16:
+		w.header(0xda, 2, n)
+	default:
+		w.header(0xdb, 4, n)
+	}
+	w.Buffer.WriteString(s)
+}
+
+func (w *msgpackWriter) Bytes(b []byte) {
+	n := uint64(len(b))
+	switch {
+	case n <= math.MaxUint8:
+		w.header(0xc4, 1, n)
+	case n <= math.MaxUint16:
+		w.header(0xc5, 2, n)
+	default:
+		w.header(0xc6, 4, n)
+	}
+	w.Buffer.Write(b)
+}
+
+func (w *msgpackWriter) ArrayHeader(n int) {
+	switch {
+	case n < 16:
+		w.Buffer.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		w.header(0xdc, 2, uint64(n))
+	default:
+		w.header(0xdd, 4, uint64(n))
+	}
+}
+
+func (w *msgpackWriter) MapHeader(n int) {
+	switch {
+	case n < 16:
+		w.Buffer.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		w.header(0xde, 2, uint64(n))
+	default:
+		w.header(0xdf, 4, uint64(n))
+	}
+}
+
+// Write the type byte followed by the value in big endian, with the given size
+func (w *msgpackWriter) header(t byte, size int, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	w.Buffer.WriteByte(t)
+	w.Buffer.Write(b[8-size:])
+}
+
+// Reads MessagePack values as generic values
+type msgpackReader struct {
+	*bytes.Reader
+	depth int
+}
+
+func (r *msgpackReader) Value() (interface{}, error) {
+
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > maxBinaryDepth {
+		return nil, fmt.Errorf("The data is nested more than %d levels", maxBinaryDepth)
+	}
+
+	t, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xf0 == 0x80:
+		return r.readMap(uint64(t & 0x0f))
+	case t&0xf0 == 0x90:
+		return r.readArray(uint64(t & 0x0f))
+	case t&0xe0 == 0xa0:
+		return r.readString(uint64(t & 0x1f))
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (t - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readFull(r.Reader, n)
+
+	case 0xca:
+		n, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := r.uint(8)
+		return math.Float64frombits(n), err
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return r.uint(1 << (t - 0xcc))
+
+	case 0xd0:
+		n, err := r.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := r.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := r.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := r.uint(8)
+		return int64(n), err
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (t - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.readString(n)
+
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (t - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.readArray(n)
+
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (t - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.readMap(n)
+	}
+
+	return nil, fmt.Errorf("MessagePack type 0x%x is not supported", t)
+}
+
+// Read an unsigned number in big endian with the given size
+func (r *msgpackReader) uint(size int) (uint64, error) {
+	b, err := readFull(r.Reader, uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (r *msgpackReader) readString(n uint64) (interface{}, error) {
+	b, err := readFull(r.Reader, n)
+	return string(b), err
+}
+
+func (r *msgpackReader) readArray(n uint64) (interface{}, error) {
+	// Each value has at least one byte, so the data can't have more than it
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	list := make([]interface{}, n)
+	for i := range list {
+		v, err := r.Value()
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (r *msgpackReader) readMap(n uint64) (interface{}, error) {
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	m := make(map[string]interface{}, n)
+	for i := uint64(0); i < n; i++ {
+		k, err := r.Value()
+		if err != nil {
+			return nil, err
+		}
+		key, err := genericKey(k)
+		if err != nil {
+			return nil, err
+		}
+		v, err := r.Value()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// Return the map key as a string, since the resources only have string keys
+// Numbers are accepted too, like encoding/json does for maps of ints
+func genericKey(k interface{}) (string, error) {
+	switch key := k.(type) {
+	case string:
+		return key, nil
+	case []byte:
+		return string(key), nil
+	case int64:
+		return strconv.FormatInt(key, 10), nil
+	case uint64:
+		return strconv.FormatUint(key, 10), nil
+	}
+	return "", fmt.Errorf("Map keys of type %T are not supported", k)
+}
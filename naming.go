@@ -0,0 +1,96 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Turns the names of the fields and of the actions into the segments of the paths
+// Ex: the field OrderItems and the method GETLoginHistory
+type NamingStrategy func(name string) string
+
+var (
+	// orderitems, loginhistory
+	LowerCase NamingStrategy = strings.ToLower
+
+	// order-items, login-history
+	KebabCase NamingStrategy = func(name string) string { return joinWords(name, "-") }
+
+	// order_items, login_history
+	SnakeCase NamingStrategy = func(name string) string { return joinWords(name, "_") }
+)
+
+// The NamingStrategy used by the Resources created after it is set
+// A field tagged api:"name=..." keeps the name given in the tag
+var Naming = LowerCase
+
+// Return the name of the Resource created by the field
+// It is the name in the api tag, or the field name in the Naming strategy
+func resourceName(field reflect.StructField) string {
+	if name, ok := apiOption(field.Tag, "name"); ok && len(name) > 0 {
+		return name
+	}
+	return Naming(field.Name)
+}
+
+// Return the value of an option of the api tag, like api:"name=order-items"
+func apiOption(tag reflect.StructTag, key string) (string, bool) {
+	for _, opt := range strings.Split(tag.Get("api"), ",") {
+		k, v, found := strings.Cut(strings.TrimSpace(opt), "=")
+		if found && k == key {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
+
+// Return all the values of an option that can be repeated, like api:"tag=users,tag=admin"
+func apiOptions(tag reflect.StructTag, key string) []string {
+	var values []string
+	for _, opt := range strings.Split(tag.Get("api"), ",") {
+		k, v, found := strings.Cut(strings.TrimSpace(opt), "=")
+		if found && k == key {
+			values = append(values, strings.TrimSpace(v))
+		}
+	}
+	return values
+}
+
+// Return true if the field is left out of the Resource tree by the tag api:"-"
+func isIgnoredField(field reflect.StructField) bool {
+	return hasAPIOption(field.Tag, "-")
+}
+
+// Return the words of the name in lower case joined by the separator
+// An upper case letter starts a new word, but acronyms are kept together: HTTPServer is http-server
+func joinWords(name, sep string) string {
+	runes := []rune(name)
+	words := []string{}
+	start := 0
+
+	for i := 1; i < len(runes); i++ {
+		prev, curr := runes[i-1], runes[i]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+		switch {
+		case curr == '_' || curr == '-':
+			words = append(words, string(runes[start:i]))
+			start = i + 1
+			continue
+		case unicode.IsUpper(curr) && (unicode.IsLower(prev) || unicode.IsDigit(prev)),
+			unicode.IsUpper(curr) && unicode.IsUpper(prev) && nextIsLower:
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+
+	nonEmpty := words[:0]
+	for _, w := range words {
+		if len(w) > 0 {
+			nonEmpty = append(nonEmpty, strings.ToLower(w))
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
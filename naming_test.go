@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestJoinWords(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Kebab string
+		Snake string
+	}{
+		{"Items", "items", "items"},
+		{"OrderItems", "order-items", "order_items"},
+		{"LoginHistory", "login-history", "login_history"},
+		{"HTTPServer", "http-server", "http_server"},
+		{"UserID", "user-id", "user_id"},
+		{"Order2Items", "order2-items", "order2_items"},
+		{"Old_Name", "old-name", "old_name"},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		if kebab := KebabCase(test.Name); kebab != test.Kebab {
+			t.Errorf("Expected %s in kebab-case as %q, got %q", test.Name, test.Kebab, kebab)
+		}
+		if snake := SnakeCase(test.Name); snake != test.Snake {
+			t.Errorf("Expected %s in snake_case as %q, got %q", test.Name, test.Snake, snake)
+		}
+	}
+}
+
+type StoreAPI struct {
+	OrderItems OrderItemList
+	Customers  CustomerList `api:"name=clients"`
+	Internal   Customer     `api:"-"`
+}
+
+type OrderItemList []OrderItem
+
+func (l *OrderItemList) GETLoginHistory() {}
+
+type OrderItem struct{}
+
+func (o *OrderItem) GET() {}
+
+type CustomerList []Customer
+
+type Customer struct{}
+
+func (c *Customer) GET() {}
+
+func TestRouteNames(t *testing.T) {
+	tests := []struct {
+		Naming NamingStrategy
+		URI    string
+		Status int
+	}{
+		{LowerCase, "/storeapi/orderitems/1", http.StatusNoContent},
+		{LowerCase, "/storeapi/orderitems/loginhistory", http.StatusNoContent},
+		{LowerCase, "/storeapi/clients/1", http.StatusNoContent},
+		{LowerCase, "/storeapi/customers/1", http.StatusNotFound},
+		{LowerCase, "/storeapi/internal", http.StatusNotFound},
+		{KebabCase, "/store-api/order-items/1", http.StatusNoContent},
+		{KebabCase, "/store-api/order-items/login-history", http.StatusNoContent},
+		{KebabCase, "/store-api/clients/1", http.StatusNoContent},
+		{SnakeCase, "/store_api/order_items/login_history", http.StatusNoContent},
+		{SnakeCase, "/store_api/orderitems/1", http.StatusNotFound},
+	}
+
+	defer func(naming NamingStrategy) { Naming = naming }(Naming)
+
+	for _, test := range tests {
+		Naming = test.Naming
+		route := newTestRoute(t, StoreAPI{})
+
+		res := serve(t, route, "GET", test.URI)
+		if res.Code != test.Status {
+			t.Errorf("%s: expected the status %d, got %d %s", test.URI, test.Status, res.Code, res.Body)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// Encodes the responses in newline delimited JSON
+// Each element of a Slice, iterator or channel is written in its own line, as soon as it is encoded,
+// other values are written in a single line
+type NDJSONEncoder struct{}
+
+func (e NDJSONEncoder) MediaType() string {
+	return "application/x-ndjson"
+}
+
+func (e NDJSONEncoder) Encode(w io.Writer, v interface{}) error {
+	// The json.Encoder ends each value with a new line
+	encoder := json.NewEncoder(w)
+
+	isList, err := eachElem(reflect.ValueOf(v), func(elem reflect.Value) error {
+		return encoder.Encode(elem.Interface())
+	})
+	if isList || err != nil {
+		return err
+	}
+
+	return encoder.Encode(v)
+}
+
+func (e NDJSONEncoder) streams() {}
+
+func (e NDJSONEncoder) rows() {}
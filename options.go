@@ -0,0 +1,59 @@
+package api
+
+import (
+	"log/slog"
+)
+
+// What to do when some Init method returns an error
+// and neither the handler nor any other Init method asks for the errors
+type InitErrorPolicy int
+
+const (
+	// Stop the chain and answer the error, with its status or 500
+	AbortOnInitError InitErrorPolicy = iota
+	// Go ahead and run the handler anyway, the error is just dropped
+	IgnoreInitErrors
+)
+
+// Options used to answer the requests of a Route
+// A Route without Options uses the Options of its Parent
+type Options struct {
+	InitErrors InitErrorPolicy
+
+	// Construct the dependencies that don't depend on each other
+	// at the same time, following the plan created with the Route
+	Parallel bool
+
+	// Wraps the bodies of the responses and errors, nil sends them as they are
+	Envelope *Envelope
+
+	// Send in the links of the Envelope the links found in the Route tree:
+	// self, up, the children and the actions of the resource
+	Links bool
+
+	// Time each Init method and the handler, and send the timings in the Server-Timing header
+	ServerTiming bool
+
+	// Receives the timings of each request, it also turns the timing on
+	Observer TimingObserver
+
+	// Writes one line for each request answered, and it is the base of the *slog.Logger
+	// the handlers and Init methods ask for, nil uses slog.Default without writing the lines
+	Logger *slog.Logger
+}
+
+// Used when no Route in the tree has Options
+var defaultOptions = &Options{
+	InitErrors: AbortOnInitError,
+}
+
+// Return the Options of this Route,
+// or of the first parent that has Options
+func (ro *Route) options() *Options {
+	for r := ro; r != nil; r = r.Parent {
+		if r.Options != nil {
+			return r.Options
+		}
+	}
+	return defaultOptions
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Where an input of a method reads its value from
+type argKind int
+
+const (
+	slotArg       argKind = iota // The Value in the slot
+	elemArg                      // The Elem of the Ptr in the slot
+	errorArg                     // The first error, or a nil error
+	errorSliceArg                // All the errors
+	idArg                        // The ID of the requester caught in the URI
+	fileArg                      // The first File sent, or an empty File
+	fileSliceArg                 // All the Files sent
+)
+
+// Slots of the context types, the dependencies come after them
+const (
+	writerSlot = iota
+	requestSlot
+	metaSlot
+	loggerSlot
+	firstDependencySlot
+)
+
+type argument struct {
+	Kind argKind
+	Slot int
+	ID   reflect.Type // The requester Type, used as key in the ID Map
+}
+
+// One dependency to be constructed and the slot it will be stored
+type step struct {
+	Dependency *dependency
+	Slot       int
+
+	// The inputs of its Init method
+	// The first one is always the dependency itself
+	Args []argument
+
+	// The index of the Init outputs that are errors
+	Errors []int
+
+	// The index of the Init output that is the dependency itself, -1 if none
+	// and true if it outputs an Elem insted of a Ptr to the Elem
+	Self     int
+	SelfElem bool
+}
+
+// The flat execution plan of a Handler
+// It is compiled once, when the Route is created,
+// so no Type needs to be compared when answering a request
+type plan struct {
+	// All steps in the order they should be constructed
+	Steps []*step
+
+	// The same steps grouped by levels
+	// Steps in the same level don't depend on each other,
+	// so they can be constructed at the same time
+	Levels [][]*step
+
+	// The inputs of the Handler method
+	Args []argument
+
+	// Number of slots to store the context values and dependencies
+	Slots int
+
+	// True if the resource of the handler is bound from the request body,
+	// only PUT and POST are expected to have a body
+	Body bool
+
+	// True if some method reads the *http.Request itself,
+	// so it reads the bodies no Decoder does
+	Raw bool
+
+	// True if some method asks for the Files sent in the request
+	Files bool
+
+	// True if some method asks for the *slog.Logger of the request
+	Logger bool
+}
+
+// Create the execution plan of the Handler
+// It should be called after the check for Circular Dependency
+func newPlan(h *handler) (*plan, error) {
+
+	// Level of each dependency already placed in the plan
+	levels := map[*dependency]int{}
+	grouped := [][]*dependency{}
+
+	// The dependencies are visited in the same order they are requested,
+	// the handler inputs first, so the plan order is deterministic
+	for _, t := range h.Method.Inputs {
+		_, err := placeDependency(t, h, levels, &grouped)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := &plan{
+		Steps:  []*step{},
+		Levels: make([][]*step, len(grouped)),
+		Slots:  firstDependencySlot,
+	}
+
+	// Give a slot for each dependency, level by level
+	slots := map[*dependency]int{}
+	for i, level := range grouped {
+		for _, d := range level {
+			s := &step{
+				Dependency: d,
+				Slot:       p.Slots,
+				Self:       -1,
+			}
+			slots[d] = s.Slot
+			p.Slots++
+
+			p.Steps = append(p.Steps, s)
+			p.Levels[i] = append(p.Levels[i], s)
+		}
+	}
+
+	// Now that every dependency has a slot, compile the inputs
+	for _, s := range p.Steps {
+		err := s.compile(h, slots)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args, err := compileArgs(h.Method, h, slots)
+	if err != nil {
+		return nil, err
+	}
+	p.Args = args
+
+	p.Body = h.Method.HTTPMethod == "PUT" || h.Method.HTTPMethod == "POST"
+
+	// The Files should be read before constructing anything
+	p.Files = usesFiles(p.Args)
+	p.Logger = usesSlot(p.Args, loggerSlot)
+	p.Raw = usesSlot(p.Args, requestSlot)
+	for _, s := range p.Steps {
+		p.Files = p.Files || usesFiles(s.Args)
+		p.Logger = p.Logger || usesSlot(s.Args, loggerSlot)
+		p.Raw = p.Raw || usesSlot(s.Args, requestSlot)
+	}
+
+	return p, nil
+}
+
+// Return true if some of the arguments is a File or []File
+func usesFiles(args []argument) bool {
+	for _, arg := range args {
+		if arg.Kind == fileArg || arg.Kind == fileSliceArg {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if some of the arguments is the Value in the slot
+func usesSlot(args []argument, slot int) bool {
+	for _, arg := range args {
+		if arg.Kind == slotArg && arg.Slot == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// Place the dependency of the given Type after all its Init dependencies
+// Return the level where it was placed, or -1 for context types
+func placeDependency(t reflect.Type, h *handler, levels map[*dependency]int, grouped *[][]*dependency) (int, error) {
+
+	// Context types are always present, they don't need to be constructed
+	if isContextType(t) {
+		return -1, nil
+	}
+
+	d, exist := h.Dependencies.vaueOf(t)
+	if !exist { // It should never occurs!
+		return 0, fmt.Errorf("No dependency %s found for %s", t, h)
+	}
+
+	level, placed := levels[d]
+	if placed {
+		return level, nil
+	}
+
+	// It is one level after its deepest dependency
+	level = 0
+	if d.Method != nil {
+		for _, input := range d.Method.Inputs {
+			// The first element will always be the dependency itself
+			if d.isType(input) {
+				continue
+			}
+
+			l, err := placeDependency(input, h, levels, grouped)
+			if err != nil {
+				return 0, err
+			}
+			if l+1 > level {
+				level = l + 1
+			}
+		}
+	}
+
+	levels[d] = level
+
+	for len(*grouped) <= level {
+		*grouped = append(*grouped, []*dependency{})
+	}
+	(*grouped)[level] = append((*grouped)[level], d)
+
+	return level, nil
+}
+
+// Compile the Init inputs and outputs of this step
+func (s *step) compile(h *handler, slots map[*dependency]int) error {
+
+	m := s.Dependency.Method
+	if m == nil {
+		return nil
+	}
+
+	args, err := compileArgs(m, h, slots)
+	if err != nil {
+		return err
+	}
+
+	// The first input is the dependency itself,
+	// it isn't indexed in the dependencies by the Init receiver Type
+	args[0] = argument{Kind: slotArg, Slot: s.Slot}
+	if m.Inputs[0].Kind() != reflect.Ptr {
+		args[0].Kind = elemArg
+	}
+	s.Args = args
+
+	for i, t := range m.Outputs {
+		if t == errorType {
+			s.Errors = append(s.Errors, i)
+			continue
+		}
+		// Check if this output is the dependency itself
+		if s.Dependency.isType(t) {
+			s.Self = i
+			s.SelfElem = t.Kind() != reflect.Ptr
+		}
+	}
+
+	return nil
+}
+
+// Compile where each input of the method reads its value from
+func compileArgs(m *method, h *handler, slots map[*dependency]int) ([]argument, error) {
+
+	args := make([]argument, len(m.Inputs))
+
+	for i, t := range m.Inputs {
+
+		switch {
+		case t == errorType:
+			args[i] = argument{Kind: errorArg}
+
+		case t == errorSliceType:
+			args[i] = argument{Kind: errorSliceArg}
+
+		case t == idPtrType:
+			// The IDs are indexed by the Ptr Type of the requester
+			args[i] = argument{Kind: idArg, ID: ptrOfType(m.Owner)}
+
+		case t == fileType:
+			args[i] = argument{Kind: fileArg}
+
+		case t == fileSliceType:
+			args[i] = argument{Kind: fileSliceArg}
+
+		case t.AssignableTo(tesponseWriterType):
+			args[i] = argument{Kind: slotArg, Slot: writerSlot}
+
+		case t == requestPtrType:
+			args[i] = argument{Kind: slotArg, Slot: requestSlot}
+
+		case t == metaPtrType:
+			args[i] = argument{Kind: slotArg, Slot: metaSlot}
+
+		case t == loggerPtrType:
+			args[i] = argument{Kind: slotArg, Slot: loggerSlot}
+
+		default:
+			d, exist := h.Dependencies.vaueOf(t)
+			if !exist {
+				return nil, fmt.Errorf("No dependency %s found for %s", t, h)
+			}
+
+			args[i] = argument{Kind: slotArg, Slot: slots[d]}
+
+			// It is requiring the Elem itself of the Ptr stored
+			if t.Kind() == reflect.Struct || t.Kind() == reflect.Slice {
+				args[i].Kind = elemArg
+			}
+		}
+	}
+
+	return args, nil
+}
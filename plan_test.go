@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A deep tree of dependencies, each Init depends on the next level
+type DeepAPI struct {
+	Deep Deep
+}
+
+type Deep struct {
+	Sum int
+}
+
+type Level1 struct{ N int }
+type Level2 struct{ N int }
+type Level3 struct{ N int }
+type Level4 struct{ N int }
+type Level5 struct{ N int }
+type Level6 struct{ N int }
+type Level7 struct{ N int }
+type Level8 struct{ N int }
+
+func (l *Level1) Init(next Level2, id *ID)                      { l.N = next.N + 1 }
+func (l *Level2) Init(next Level3, id *ID)                      { l.N = next.N + 1 }
+func (l *Level3) Init(next Level4, id *ID)                      { l.N = next.N + 1 }
+func (l *Level4) Init(next Level5, id *ID)                      { l.N = next.N + 1 }
+func (l *Level5) Init(next Level6, id *ID)                      { l.N = next.N + 1 }
+func (l *Level6) Init(next Level7, id *ID)                      { l.N = next.N + 1 }
+func (l *Level7) Init(next Level8, id *ID)                      { l.N = next.N + 1 }
+func (l *Level8) Init(w http.ResponseWriter, req *http.Request) { l.N = 1 }
+
+func (d *Deep) GET(l1 *Level1, l4 Level4, l8 *Level8, err error) *Deep {
+	d.Sum = l1.N + l4.N + l8.N
+	return d
+}
+
+func TestDeepPlan(t *testing.T) {
+	route := newTestRoute(t, DeepAPI{})
+
+	h := route.Children["deep"].Handlers["GET"]
+
+	// The receiver and the 8 levels
+	if len(h.Plan.Steps) != 9 {
+		t.Errorf("Expected 9 steps in the plan, got %d", len(h.Plan.Steps))
+	}
+
+	// Each dependency comes after the ones it depends on
+	for i, s := range h.Plan.Steps {
+		if s.Dependency.Method == nil {
+			continue
+		}
+		for _, arg := range s.Args[1:] {
+			if arg.Kind == slotArg || arg.Kind == elemArg {
+				if arg.Slot >= s.Slot {
+					t.Errorf("Step %d reads slot %d before it is constructed", i, arg.Slot)
+				}
+			}
+		}
+	}
+
+	res := serve(t, route, "GET", "/deepapi/deep")
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, res.Code, res.Body)
+	}
+}
+
+func benchmarkDeepTree(b *testing.B, options *Options) {
+	resource, err := NewResource(DeepAPI{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	route, err := NewRoute(resource)
+	if err != nil {
+		b.Fatal(err)
+	}
+	route.Options = options
+
+	req, _ := http.NewRequest("GET", "/deepapi/deep", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		route.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkDeepTree(b *testing.B) {
+	benchmarkDeepTree(b, nil)
+}
+
+func BenchmarkDeepTreeParallel(b *testing.B) {
+	benchmarkDeepTree(b, &Options{Parallel: true})
+}
+
+// Construct the dependencies and call the handler of the deep tree,
+// without routing the request nor encoding the response
+func BenchmarkDeepRun(b *testing.B) {
+	resource, err := NewResource(DeepAPI{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	route, err := NewRoute(resource)
+	if err != nil {
+		b.Fatal(err)
+	}
+	h := route.Children["deep"].Handlers["GET"]
+
+	req, _ := http.NewRequest("GET", "/deepapi/deep", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		out, err := newContext(h, httptest.NewRecorder(), req, idMap{}, &Meta{}, nil).run()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if out[0].Interface().(*Deep).Sum != 14 {
+			b.Fatal("Unexpected result", out[0].Interface())
+		}
+	}
+}
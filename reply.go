@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// The answer of a request
+// The bodies are encoded by the negotiated Encoder and wrapped in the envelope of the Route
+type reply struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+	Route   *Route  // The Route that answers, its Options decide the envelope
+	Encoder Encoder // Nil until it is negotiated
+	IDs     idMap   // The IDs caught in the URI
+	Meta    *Meta
+}
+
+func newReply(w http.ResponseWriter, req *http.Request, ro *Route) *reply {
+	return &reply{
+		Writer:  w,
+		Request: req,
+		Route:   ro,
+		Meta:    &Meta{},
+	}
+}
+
+// Choose the Encoder by the Accept header of the request
+func (r *reply) negotiate() error {
+	e, err := negotiate(r.Request.Header.Get("Accept"))
+	if err != nil {
+		return err
+	}
+	r.Encoder = e
+	return nil
+}
+
+// Send the value with the given status
+func (r *reply) send(v interface{}, status int) {
+	var body interface{}
+	if de, ok := r.Encoder.(documentEncoder); ok {
+		body = de.document(v, r)
+	} else {
+		body = r.Route.options().Envelope.wrap(v, r)
+	}
+
+	err := writeResponse(r.Writer, r.Encoder, body, status)
+	if err != nil {
+		r.error(err, errorStatus(err))
+	}
+}
+
+// Send a response without body
+func (r *reply) noContent() {
+	r.Writer.Header().Set("Content-Type", r.Encoder.MediaType())
+	r.Writer.WriteHeader(r.status(http.StatusNoContent))
+}
+
+// Return the status of a successful response
+// If the handler created an element, its Location is set and the status is 201 Created
+func (r *reply) status(status int) int {
+	id := r.Meta.created()
+	if id == nil {
+		return status
+	}
+
+	ids := idMap{}
+	ids.extend(r.IDs)
+
+	var location string
+	if r.Route.IsSlice && r.Route.Elem != nil {
+		ids[ptrOfType(r.Route.Elem.Value.Type())] = reflect.ValueOf(id)
+		location, _ = r.Route.Elem.path(ids)
+	} else {
+		self, _ := r.Route.path(ids)
+		location = self + "/" + id.String()
+	}
+
+	r.Writer.Header().Set("Location", location)
+	return http.StatusCreated
+}
+
+// Send the error with the given status
+// If the request doesn't accept any media type, the error is sent with the default Encoder
+func (r *reply) error(err error, status int) {
+	e := r.Encoder
+	if e == nil {
+		e = defaultEncoder()
+		if nerr := r.negotiate(); nerr == nil {
+			e = r.Encoder
+		}
+	}
+
+	var body interface{}
+	if de, ok := e.(documentEncoder); ok {
+		body = de.errorDocument(err, status, r)
+	} else {
+		body = r.Route.options().Envelope.wrapError(e, err, r)
+	}
+	writeError(r.Writer, e, body, status)
+}
@@ -3,7 +3,6 @@ package api
 import (
 	"fmt"
 	"reflect"
-	"strings"
 )
 
 // We are storing the Pointer to Struct value and Pointer to Slice as Value
@@ -59,10 +58,10 @@ func newResource(value reflect.Value, field reflect.StructField, parent *Resourc
 	// Garants we are working with a Ptr to Struct or Slice
 	value = ptrOfValue(value)
 
-	//log.Println("Scanning Struct:", value.Type(), "name:", strings.ToLower(field.Name), value.Interface())
+	//log.Println("Scanning Struct:", value.Type(), "name:", resourceName(field), value.Interface())
 
 	resource := &Resource{
-		Name:      strings.ToLower(field.Name),
+		Name:      resourceName(field),
 		Value:     value,
 		Parent:    parent,
 		Children:  []*Resource{},
@@ -104,7 +103,8 @@ func newResource(value reflect.Value, field reflect.StructField, parent *Resourc
 
 		// Check if this field is exported: fieldValue.CanInterface()
 		// and if this field is valid fo create Resources: Structs or Slices of Structs
-		if isValidValue(fieldValue) {
+		// The fields tagged api:"-" are left out of the tree
+		if isValidValue(fieldValue) && !isIgnoredField(field) {
 			child, err := newResource(fieldValue, field, resource)
 			if err != nil {
 				return nil, err
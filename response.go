@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Embedded in the Struct returned by a handler to answer each of its fields
+// as a named output, in the order of the fields
+// The names follow the json tags, so they can be chosen even for outputs of the same type
+// Ex:
+//
+//	type Login struct {
+//		api.Response
+//		User    *User  `json:"user"`
+//		Token   string `json:"token"`
+//		Refresh string `json:"refresh,omitempty"`
+//	}
+//
+// Like the multiple outputs, nil fields are not sent and errors are sent as its messages
+type Response struct{}
+
+var responseType = reflect.TypeOf(Response{})
+
+// Return the fields answered as outputs if the Type embeds api.Response
+func responseFields(t reflect.Type) []jsonField {
+	t = elemOfType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type == responseType {
+			return jsonFields(t)
+		}
+	}
+
+	return nil
+}
+
+// Return an error if two outputs of the method have the same name,
+// or if some of them has no name
+func (m *method) checkOutputNames() error {
+
+	names := m.OutName
+	if m.Response != nil {
+		names = make([]string, len(m.Response))
+		for i, f := range m.Response {
+			names[i] = f.Name
+		}
+	} else if m.NumOut < 2 {
+		return nil // A single output is sent by itself
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if len(name) == 0 {
+			return fmt.Errorf("The method %s has an output without name, return an api.Response to name it", m.Method.Name)
+		}
+		if seen[name] {
+			return fmt.Errorf("The method %s has two outputs named '%s', return an api.Response to name them", m.Method.Name, name)
+		}
+		seen[name] = true
+	}
+
+	return nil
+}
+
+// Return the outputs of the handler by its names, in order
+// The outputs come from the fields of an api.Response, or from the method outputs
+func (h *handler) outputs(out []reflect.Value) outputMap {
+
+	response := outputMap{}
+
+	if h.Method.Response != nil {
+		v := out[0]
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return response
+			}
+			v = v.Elem()
+		}
+		for _, f := range h.Method.Response {
+			fv := fieldValue(v, f)
+			if fv.IsValid() && !(f.OmitEmpty && isEmptyValue(fv)) {
+				response.addValue(f.Name, f.Type, fv)
+			}
+		}
+		return response
+	}
+
+	for i, v := range out {
+		response.addValue(h.Method.OutName[i], h.Method.Outputs[i], v)
+	}
+
+	return response
+}
+
+// Add the output of the given Type, unless it is nil
+func (m *outputMap) addValue(name string, t reflect.Type, v reflect.Value) {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		if v.IsNil() {
+			return
+		}
+	}
+
+	// Error is printing empty structs, treat that...
+	if t == errorType {
+		m.add(name, v.Interface().(error).Error())
+		return
+	}
+	if t == errorSliceType {
+		errs := ""
+		for _, err := range v.Interface().([]error) {
+			errs += err.Error() + ". "
+		}
+		m.add(name, errs)
+		return
+	}
+
+	m.add(name, v.Interface())
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type SessionAPI struct {
+	Session Session
+}
+
+type Session struct{}
+
+type Login struct {
+	Response
+	Token   string  `json:"token"`
+	User    *Author `json:"user"`
+	Admin   *Author `json:"admin"`
+	Refresh string  `json:"refresh,omitempty"`
+	Warning error   `json:"warning"`
+	ignored string
+}
+
+func (s *Session) POST() *Login {
+	return &Login{
+		Token:   "abc",
+		User:    &Author{Name: "Ana"},
+		Warning: errors.New("Password expires soon"),
+	}
+}
+
+func (s *Session) GET() Login {
+	return Login{Token: "abc"}
+}
+
+type TwinAPI struct {
+	Twin Twin
+}
+
+type Twin struct{}
+
+func (t *Twin) GET() (*Book, *Book) {
+	return nil, nil
+}
+
+type NamelessAPI struct {
+	Nameless Nameless
+}
+
+type Nameless struct{}
+
+func (n *Nameless) GET() (map[string]int, error) {
+	return nil, nil
+}
+
+type DuplicatedAPI struct {
+	Duplicated Duplicated
+}
+
+type Duplicated struct{}
+
+type DuplicatedResponse struct {
+	Response
+	Name   string
+	Second string `json:"Name"`
+}
+
+func (d *Duplicated) GET() DuplicatedResponse {
+	return DuplicatedResponse{}
+}
+
+func TestResponseOutputs(t *testing.T) {
+	route := newTestRoute(t, SessionAPI{})
+
+	tests := []struct {
+		Method   string
+		Expected string
+	}{
+		{"POST", `{"token":"abc","user":{"name":"Ana"},"warning":"Password expires soon"}`},
+		{"GET", `{"token":"abc"}`},
+	}
+
+	for _, test := range tests {
+		res := serve(t, route, test.Method, "/sessionapi/session")
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", test.Method, http.StatusOK, res.Code, res.Body)
+		}
+		body := &bytes.Buffer{}
+		json.Compact(body, res.Body.Bytes())
+		if body.String() != test.Expected {
+			t.Errorf("%s: expected %s, got %s", test.Method, test.Expected, body)
+		}
+	}
+}
+
+func TestOutputNamesCollision(t *testing.T) {
+	tests := []struct {
+		API      interface{}
+		Contains string
+	}{
+		{TwinAPI{}, "two outputs named 'book'"},
+		{NamelessAPI{}, "output without name"},
+		{DuplicatedAPI{}, "two outputs named 'Name'"},
+	}
+
+	for _, test := range tests {
+		resource, err := NewResource(test.API)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewRoute(resource)
+		if err == nil || !strings.Contains(err.Error(), test.Contains) {
+			t.Errorf("%T: expected an error with %q, got %v", test.API, test.Contains, err)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package api
 
 import (
-	"encoding/json"
+	stdcontext "context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 )
 
 type Route struct {
@@ -30,11 +32,56 @@ type Route struct {
 
 	// True if the resource is an Slice of Resources
 	IsSlice bool
+
+	// The Route that contains this one, nil for the root Route
+	Parent *Route
+
+	// How to answer the requests of this Route and its children
+	// If it is nil, the Options of the Parent are used
+	Options *Options
+
+	// The radix tree used to find the handlers of this Route and its children
+	tree *node
 }
 
 // Receives the Root Resource and interate recursively
 // creating the Route tree
+// The Route tree is also flattened in a radix tree to answer the requests
 func NewRoute(r *Resource) (*Route, error) {
+	ro, err := newRoute(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ro.tree, err = newTree(ro)
+	if err != nil {
+		return nil, err
+	}
+
+	ro.describeHandlers()
+
+	return ro, nil
+}
+
+// Describe the handlers of this Route and its children
+// It should be called when their paths are known, after the Parents are set
+func (ro *Route) describeHandlers() {
+	for _, h := range ro.Handlers {
+		h.Endpoint = h.endpoint()
+		h.Name = funcName(h.Endpoint)
+	}
+
+	if ro.IsSlice && ro.Elem != nil {
+		ro.Elem.describeHandlers()
+	}
+
+	for _, child := range ro.Children {
+		child.describeHandlers()
+	}
+}
+
+// Create the Route for the Resource and its children recursively
+func newRoute(r *Resource) (*Route, error) {
 
 	//log.Printf("Building Routes for %s\n", r)
 
@@ -61,18 +108,28 @@ func NewRoute(r *Resource) (*Route, error) {
 		return nil, err
 	}
 
+	// Now that there is no Circular Dependency
+	// plan the construction of the dependencies of each handler
+	for _, h := range ro.Handlers {
+		h.Plan, err = newPlan(h)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// If this Route is for an Slice
 	// Map the Route for this Elem
 	if r.IsSlice {
-		ro.Elem, err = NewRoute(r.Elem)
+		ro.Elem, err = newRoute(r.Elem)
 		if err != nil {
 			return nil, err
 		}
+		ro.Elem.Parent = ro
 	}
 
 	// Creating routes recursivelly for each resource child
 	for _, child := range r.Children {
-		c, err := NewRoute(child)
+		c, err := newRoute(child)
 		if err != nil {
 			return nil, err
 		}
@@ -115,38 +172,47 @@ func (ro *Route) scanRoutesFrom(r *Resource) error {
 // This type could be []*Resource or just *Resource
 func (ro *Route) scanMethods(r *Resource) error {
 
-	t := r.Value.Type()
-
-	//log.Println("Scanning methods from type", t, "is slice:", isSliceType(t))
-
-	for i := 0; i < t.NumMethod(); i++ {
+	//log.Println("Scanning methods from type", r.Value.Type(), "is slice:", isSliceType(r.Value.Type()))
 
-		m := t.Method(i)
-
-		//log.Println("Testing:", m.Name, isMappedMethod(m))
+	// We will accept all methods that
+	// has GET, POST, PUT, DELETE, HEAD
+	// in the prefix of the method name,
+	// or only the ones declared, if the resource has the Routes method
+	methods, err := mappedMethods(r)
+	if err != nil {
+		return err
+	}
 
-		// We will accept all methods that
-		// has GET, POST, PUT, DELETE, HEAD
-		// in the prefix of the method name
-		if isMappedMethod(m) {
+	// The Doc of the handlers comes from the tag of the resource,
+	// then from its Routes and Describe methods
+	doc, err := tagDoc(r)
+	if err != nil {
+		return err
+	}
+	described := describedDocs(r)
 
-			h, err := newHandler(m, r)
-			if err != nil {
-				return err
-			}
+	for _, m := range methods {
 
-			//log.Printf("Adding Handler %s for route %s\n", h, ro)
+		h, err := newHandler(m.Method, r)
+		if err != nil {
+			return err
+		}
+		h.Method.HTTPMethod = m.HTTPMethod
+		h.Method.Name = m.Name
+		h.Doc = doc.merge(m.Doc).merge(described[m.Method.Name])
+		h.Route = ro
 
-			// Check if this new Handler will conflict with some address of Handler that already exist
-			// Action Handlers Names could conflict with Children Names...
-			err = ro.checkAddrConflict(h)
-			if err != nil {
-				return err
-			}
+		//log.Printf("Adding Handler %s for route %s\n", h, ro)
 
-			// Index: GETLogin, POST, or POSTMessage...
-			ro.Handlers[h.Method.HTTPMethod+h.Method.Name] = h
+		// Check if this new Handler will conflict with some address of Handler that already exist
+		// Action Handlers Names could conflict with Children Names...
+		err = ro.checkAddrConflict(h)
+		if err != nil {
+			return err
 		}
+
+		// Index: GETLogin, POST, or POSTMessage...
+		ro.Handlers[h.Method.HTTPMethod+h.Method.Name] = h
 	}
 
 	return nil
@@ -194,13 +260,62 @@ func (ro *Route) AddChild(child *Route) error {
 			}
 		}
 
+		child.Parent = ro
 		ro.Children[child.Name] = child
 
+		// Its paths start with the path of this Route now
+		child.describeHandlers()
+
 		//log.Printf("Child name %s added %s\n", child.Name, child)
+
+		// The new handlers should be found from the Routes above it
+		return ro.rebuildTrees()
 	}
 	return nil
 }
 
+// Rebuild the radix tree of this Route and of its parents that have one
+func (ro *Route) rebuildTrees() error {
+	for r := ro; r != nil; r = r.Parent {
+		if r.tree == nil {
+			continue
+		}
+		tree, err := newTree(r)
+		if err != nil {
+			return err
+		}
+		r.tree = tree
+	}
+	return nil
+}
+
+// Return the handler for the HTTP method and the requested path
+// Fulfill the ID Map with IDs present in the path
+func (ro *Route) handler(path string, httpMethod string, ids idMap) (*handler, error) {
+
+	tree := ro.tree
+
+	// This Route wasn't created by NewRoute, like the children Routes
+	if tree == nil {
+		var err error
+		tree, err = newTree(ro)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ps := params{}
+
+	h := tree.lookup(path, httpMethod, &ps)
+	if h == nil {
+		return nil, fmt.Errorf("Not found any handler for [%s] %s in the %s", httpMethod, path, ro)
+	}
+
+	ps.extend(ids)
+
+	return h, nil
+}
+
 // Check if this new Handler will conflict with some Handler already created
 // Action Handlers Names could conflict with Children Names...
 func (ro *Route) checkAddrConflict(h *handler) error {
@@ -223,148 +338,115 @@ func (ro *Route) String() string {
 	return fmt.Sprintf("Route: [%s] %s", ro.Name, ro.Value.Type())
 }
 
-// Return the Route from the especified Name
-// Fulfill the ID Map with IDs present in the requested URI
-func (ro *Route) handler(uri []string, httpMethod string, ids idMap) (*handler, error) {
-
-	//log.Println("Route Handling", uri, "in the", ro)
-
-	// Check if is trying to request some Handler of this Route
-	if len(uri) == 0 {
-		h, exist := ro.Handlers[httpMethod]
-		if !exist {
-			return nil, fmt.Errorf("Method %s not found in the %s", httpMethod, ro)
-		}
-		return h, nil
-	}
-
-	// Check if is trying to request some Action Handler of this Route
-	if len(uri) == 1 {
-
-		h, exist := ro.Handlers[httpMethod+uri[0]]
-		if exist {
-			return h, nil
-		}
-
-		// It is not an error, cause could have an resources with this name, not an action
-		//log.Println("Action " + httpMethod + uri[0] + " NOT FOUND")
-	}
-
-	// If we are in a Slice Route, get its ID and search in the Elem Route
-	if ro.IsSlice {
-		// Add its ID to the Map
-		id := &ID{id: uri[0]}
-		ids[ro.Elem.Value.Type()] = reflect.ValueOf(id)
-
-		return ro.Elem.handler(uri[1:], httpMethod, ids)
-	}
-
-	// If we are in an Elem Route, the only possibility is to have a Child with this Name
-	child, exist := ro.Children[uri[0]]
-	if exist {
-		return child.handler(uri[1:], httpMethod, ids)
-	}
-
-	return nil, fmt.Errorf("Not exist any Child '%s' or Action '%s' in the %s", uri[0], httpMethod+strings.Title(uri[0]), ro)
-}
-
 // Implementing the http.Handler Interface
-// TODO: Error messages should be sent in JSON
+// The response is encoded in the media type the request accepts
 func (ro *Route) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	//log.Println("### Serving the resource", req.URL.RequestURI())
 
+	// Each request answered is logged, if there is a Logger
+	start := time.Now()
+	w, access := startAccessLog(w, req, ro, start)
+	if access != nil {
+		defer access.write(req)
+	}
+
 	// Get the resource identfiers from the URL
 	// Remember to descart the query string: ?q=sfxt&x=132...
 	// Remember to descart the first empty element of the list
-	uri := strings.Split(strings.Split(req.URL.RequestURI(), "?")[0], "/")[1:]
+	path := strings.Split(req.URL.RequestURI(), "?")[0]
+	uri := strings.Split(path, "/")[1:]
+
+	r := newReply(w, req, ro)
 
 	// Check if the requested URI maches with this main Route
 	if ro.Name != uri[0] {
-		writeError(w, errors.New("Route "+ro.Name+" not match with "+uri[0]), http.StatusNotFound)
+		r.error(errors.New("Route "+ro.Name+" not match with "+uri[0]), http.StatusNotFound)
 		return
 	}
 
 	// Store the IDs of the resources in the URI
 	ids := idMap{}
 
-	handler, err := ro.handler(uri[1:], req.Method, ids)
+	handler, err := ro.handler(path, req.Method, ids)
 	if err != nil {
-		writeError(w, err, http.StatusNotFound)
+		r.error(err, http.StatusNotFound)
 		return
 	}
 
 	//log.Printf("Route found: %s = %s ids: %q\n", req.URL.RequestURI(), handler, ids)
 
-	// Process the request with the found Handler
-	output := newContext(handler, w, req, ids).run()
+	// The Options of the found Route decide how to answer
+	r.Route = handler.Route
+	r.IDs = ids
 
-	// If there is no output to sent back
-	if handler.Method.NumOut == 0 {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNoContent)
-		return
+	// The found Route could have its own Logger
+	if access == nil {
+		w, access = startAccessLog(w, req, handler.Route, start)
+		if access != nil {
+			r.Writer = w
+			defer access.write(req)
+		}
 	}
 
-	// If there is just one resource to send back
-	if handler.Method.NumOut == 1 {
-
-		// Encode the output in JSON
-		jsonResponse, err := json.MarshalIndent(output[0].Interface(), "", "\t")
-		if err != nil {
-			writeError(w, errors.New("Error encoding to Json: "+err.Error()), http.StatusInternalServerError)
-			return
+	// The Logger of the request knows what answers it
+	var logger *slog.Logger
+	if access != nil || handler.Plan.Logger {
+		path, name, idAttrs := handler.logAttrs(ids)
+		logger = requestLogger(handler.Route.options().Logger, req, path, name, idAttrs)
+		if access != nil {
+			access.Path, access.Handler, access.IDs = path, name, idAttrs
 		}
+	}
+
+	// The clients are warned about deprecated handlers, even when they fail
+	setDeprecationHeaders(w, handler.Doc)
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(jsonResponse)
+	// Choose the Encoder before running the handler,
+	// nothing should be done if the response can't be sent
+	err = r.negotiate()
+	if err != nil {
+		r.error(err, errorStatus(err))
 		return
 	}
 
-	// If there is more than one output
-
-	// Trans form the method output into an slice of the values
-	// * Needed to generate a JSON response
-	response := make(map[string]interface{}, handler.Method.NumOut)
-	for i, v := range output {
-		if !v.IsNil() {
-			// Error is printing empty structs, treat that...
-			if handler.Method.Outputs[i] == errorType {
-				response[handler.Method.OutName[i]] = v.Interface().(error).Error()
-				continue
-			}
-			if handler.Method.Outputs[i] == errorSliceType {
-
-				errs := ""
-				for _, err := range v.Interface().([]error) {
-					errs += err.Error() + ". "
-				}
-				response[handler.Method.OutName[i]] = errs
-				continue
-			}
+	// Process the request with the found Handler
+	c := newContext(handler, w, req, ids, r.Meta, logger)
+	output, err := c.run()
+	if access != nil {
+		access.Errors = len(c.Errors)
+	}
 
-			response[handler.Method.OutName[i]] = v.Interface()
-		}
+	// The timings are sent even if some Init failed, it could be the slow one
+	if c.Timed {
+		reportTimings(w, req, handler, c.timings())
 	}
 
-	// Encode the output in JSON
-	jsonResponse, err := json.MarshalIndent(response, "", "\t")
 	if err != nil {
-		writeError(w, errors.New("Error encoding to Json: "+err.Error()), http.StatusInternalServerError)
+		// The client gave up, there is nobody to answer
+		if errors.Is(err, stdcontext.Canceled) {
+			if access != nil {
+				access.Canceled = true
+			}
+			return
+		}
+		r.error(err, errorStatus(err))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(jsonResponse)
-}
+	// If there is no output to sent back
+	if handler.Method.NumOut == 0 {
+		r.noContent()
+		return
+	}
 
-func writeError(w http.ResponseWriter, err error, status int) {
-	// Encode the output in JSON
-	jsonResponse, err := json.MarshalIndent(map[string]string{"error": err.Error()}, "", "\t")
-	if err != nil {
-		http.Error(w, "{error: \"Error encoding the error message to Json: "+err.Error()+"\"}", http.StatusInternalServerError)
+	// If there is just one resource to send back
+	if handler.Method.NumOut == 1 && handler.Method.Response == nil {
+		r.send(output[0].Interface(), r.status(http.StatusOK))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(jsonResponse)
+	// If there is more than one output, or an api.Response
+	// Transform the method output into the named outputs, in order
+	// * Needed to generate a single response
+	r.send(handler.outputs(output), r.status(http.StatusOK))
 }
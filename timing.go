@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The time spent in an Init method, or in the handler
+type Timing struct {
+	Name     string // The Type of the dependency, or the method of the handler
+	Duration time.Duration
+}
+
+// Receives the timings of each request answered by a Route with the Observer option,
+// like to send them to a metrics system
+// It is called by the goroutine of the request, before the response is sent
+type TimingObserver interface {
+	ObserveTimings(req *http.Request, e Endpoint, timings []Timing)
+}
+
+// Return true if the Init methods and the handler should be timed
+func (o *Options) timed() bool {
+	return o.ServerTiming || o.Observer != nil
+}
+
+// Return the timings of the Init methods that ran, in the plan order,
+// followed by the handler, if it ran
+func (c *context) timings() []Timing {
+	timings := []Timing{}
+
+	for _, s := range c.Handler.Plan.Steps {
+		if s.Dependency.Method != nil && c.Ran[s.Slot] {
+			timings = append(timings, Timing{Name: s.Dependency.Value.Type().String(), Duration: c.Durations[s.Slot]})
+		}
+	}
+
+	if c.HandlerRan {
+		timings = append(timings, Timing{Name: c.Handler.Method.Method.Name, Duration: c.HandlerDuration})
+	}
+
+	return timings
+}
+
+// Send the timings in the Server-Timing header and to the Observer of the Route
+func reportTimings(w http.ResponseWriter, req *http.Request, h *handler, timings []Timing) {
+	options := h.Route.options()
+
+	if options.ServerTiming {
+		metrics := make([]string, len(timings))
+		for i, t := range timings {
+			metrics[i] = serverTimingName(t.Name) + ";dur=" +
+				strconv.FormatFloat(float64(t.Duration)/float64(time.Millisecond), 'f', 3, 64)
+		}
+		w.Header().Set("Server-Timing", strings.Join(metrics, ", "))
+	}
+
+	if options.Observer != nil {
+		options.Observer.ObserveTimings(req, h.Endpoint, timings)
+	}
+}
+
+// Return the name as a token of the Server-Timing header
+// The characters a token can't have, like the brackets of []api.B, are replaced by _
+func serverTimingName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			return r
+		}
+		return '_'
+	}, name)
+}
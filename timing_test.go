@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type SlowAPI struct {
+	Dashboard Dashboard
+}
+
+type Dashboard struct{}
+
+func (d *Dashboard) GET(s Stats) {}
+
+func (d *Dashboard) GETBroken(b Broken) {}
+
+type Stats struct{}
+
+func (s *Stats) Init() {
+	time.Sleep(5 * time.Millisecond)
+}
+
+type Broken struct{}
+
+func (b *Broken) Init() error {
+	return errors.New("Broken")
+}
+
+// Keeps the timings it receives
+type timingRecorder struct {
+	Path    string
+	Timings []Timing
+}
+
+func (r *timingRecorder) ObserveTimings(req *http.Request, e Endpoint, timings []Timing) {
+	r.Path = e.Path
+	r.Timings = timings
+}
+
+func TestServerTiming(t *testing.T) {
+	route := newTestRoute(t, SlowAPI{})
+	recorder := &timingRecorder{}
+	route.Options = &Options{ServerTiming: true, Observer: recorder}
+
+	res := serve(t, route, "GET", "/slowapi/dashboard")
+
+	header := res.Header().Get("Server-Timing")
+	if !regexp.MustCompile(`^\*api\.Stats;dur=\d+\.\d{3}, GET;dur=\d+\.\d{3}$`).MatchString(header) {
+		t.Errorf("Unexpected Server-Timing header %q", header)
+	}
+
+	if recorder.Path != "/slowapi/dashboard" || len(recorder.Timings) != 2 {
+		t.Fatalf("Unexpected timings observed for %s: %v", recorder.Path, recorder.Timings)
+	}
+	if recorder.Timings[0].Name != "*api.Stats" || recorder.Timings[0].Duration < 5*time.Millisecond {
+		t.Errorf("Expected the Init of Stats to take 5ms, got %v", recorder.Timings[0])
+	}
+	if recorder.Timings[1].Name != "GET" {
+		t.Errorf("Expected the timing of the handler, got %v", recorder.Timings[1])
+	}
+
+	// The failing Init is timed, but the handler doesn't run
+	res = serve(t, route, "GET", "/slowapi/dashboard/broken")
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("Expected the status 500, got %d", res.Code)
+	}
+	header = res.Header().Get("Server-Timing")
+	if !regexp.MustCompile(`^\*api\.Broken;dur=\d+\.\d{3}$`).MatchString(header) {
+		t.Errorf("Unexpected Server-Timing header %q", header)
+	}
+
+	// Not timed by default
+	route.Options = nil
+	res = serve(t, route, "GET", "/slowapi/dashboard")
+	if res.Header().Get("Server-Timing") != "" {
+		t.Errorf("Expected no Server-Timing header, got %q", res.Header().Get("Server-Timing"))
+	}
+}
+
+func TestServerTimingName(t *testing.T) {
+	if name := serverTimingName("[]*api.B"); name != "__*api.B" {
+		t.Errorf("Expected the name __*api.B, got %s", name)
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Max number of IDs a path can have,
+// it is the max depth of Slices inside Slices in the Route tree
+const maxIDs = 16
+
+// Marks the ID segment of a Slice Route in the path used to build the tree
+const idMark = "\x00"
+
+// A node of the compressed radix tree used to find the handlers
+// The Route tree is flattened into it when the Route is created,
+// so a request doesn't need to walk the Route maps
+type node struct {
+	// The static part of the path this node matches
+	Prefix string
+
+	// Static children, indexed by the first byte of their Prefix
+	Indices  []byte
+	Children []*node
+
+	// The child that matches the ID segment of a Slice Route
+	// Static children have priority over it
+	Wildcard *node
+
+	// For the Wildcard node, the Elem Type the ID belongs to
+	IDType reflect.Type
+
+	// Handlers by HTTP method for the path that ends in this node
+	Handlers map[string]*handler
+}
+
+// The IDs caught in the path, in the order they appear
+type params struct {
+	Len    int
+	Types  [maxIDs]reflect.Type
+	Values [maxIDs]string
+}
+
+// Flatten the given Route tree in a new radix tree
+func newTree(ro *Route) (*node, error) {
+	root := &node{}
+	err := root.addRoute(ro, "/"+ro.Name, []reflect.Type{})
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Add all the handlers of the Route and its children
+// The path is the template of the Route, with idMark for each ID
+func (n *node) addRoute(ro *Route, path string, ids []reflect.Type) error {
+
+	if len(ids) > maxIDs {
+		return fmt.Errorf("%s has more than %d IDs in its path", ro, maxIDs)
+	}
+
+	for _, h := range ro.Handlers {
+		// Main handlers answer for the Route path,
+		// Action handlers for the Route path plus its name
+		// Ex: /api/a/bs for GET and /api/a/bs/login for GETLogin
+		// An empty action name also answers for the path with a trailing slash
+		p := path
+		if len(h.Method.Name) > 0 {
+			p += "/" + h.Method.Name
+		}
+		n.insert(p, ids, h)
+
+		if len(h.Method.Name) == 0 {
+			n.insert(path+"/", ids, h)
+		}
+	}
+
+	// The Elem of a Slice answers for the Slice path plus its ID
+	if ro.IsSlice {
+		elemIDs := append(append([]reflect.Type{}, ids...), ro.Elem.Value.Type())
+		err := n.addRoute(ro.Elem, path+"/"+idMark, elemIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, child := range ro.Children {
+		err := n.addRoute(child, path+"/"+name, ids)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Insert the handler in the node of the given path
+func (n *node) insert(path string, ids []reflect.Type, h *handler) {
+
+	parts := strings.Split(path, idMark)
+
+	current := n.static(parts[0])
+	for i, part := range parts[1:] {
+		current = current.wildcard(ids[i])
+		current = current.static(part)
+	}
+
+	if current.Handlers == nil {
+		current.Handlers = map[string]*handler{}
+	}
+
+	current.Handlers[h.Method.HTTPMethod] = h
+}
+
+// Return the node for this node Prefix plus the given static path
+// Creating it, or splitting an existing node, if needed
+func (n *node) static(path string) *node {
+
+	if len(path) == 0 {
+		return n
+	}
+
+	for i, c := range n.Indices {
+		if c != path[0] {
+			continue
+		}
+
+		child := n.Children[i]
+
+		l := commonPrefix(child.Prefix, path)
+
+		// Split the child, so the common part becomes a node by itself
+		if l < len(child.Prefix) {
+			rest := &node{}
+			*rest = *child
+			rest.Prefix = child.Prefix[l:]
+
+			*child = node{
+				Prefix:   child.Prefix[:l],
+				Indices:  []byte{rest.Prefix[0]},
+				Children: []*node{rest},
+			}
+		}
+
+		return child.static(path[l:])
+	}
+
+	child := &node{Prefix: path}
+	n.Indices = append(n.Indices, path[0])
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// Return the Wildcard child of this node, creating it if needed
+func (n *node) wildcard(t reflect.Type) *node {
+	if n.Wildcard == nil {
+		n.Wildcard = &node{IDType: t}
+	}
+	return n.Wildcard
+}
+
+// Return the handler for the HTTP method and the path after this node
+// Static children are tried first, if none of them have the handler
+// it tries the ID Wildcard, catching the ID in the params
+// It doesn't allocate memory
+func (n *node) lookup(path string, httpMethod string, ps *params) *handler {
+
+	if len(path) == 0 {
+		return n.Handlers[httpMethod]
+	}
+
+	for i, c := range n.Indices {
+		if c != path[0] {
+			continue
+		}
+		child := n.Children[i]
+		if strings.HasPrefix(path, child.Prefix) {
+			h := child.lookup(path[len(child.Prefix):], httpMethod, ps)
+			if h != nil {
+				return h
+			}
+		}
+		break
+	}
+
+	if n.Wildcard == nil || ps.Len == maxIDs {
+		return nil
+	}
+
+	// The ID goes until the end of the segment
+	end := strings.IndexByte(path, '/')
+	if end < 0 {
+		end = len(path)
+	}
+
+	ps.Types[ps.Len] = n.Wildcard.IDType
+	ps.Values[ps.Len] = path[:end]
+	ps.Len++
+
+	h := n.Wildcard.lookup(path[end:], httpMethod, ps)
+	if h == nil {
+		ps.Len-- // This ID didn't lead to any handler
+	}
+	return h
+}
+
+// Store the IDs caught in the given ID Map
+func (ps *params) extend(ids idMap) {
+	for i := 0; i < ps.Len; i++ {
+		ids[ps.Types[i]] = reflect.ValueOf(&ID{id: ps.Values[i]})
+	}
+}
+
+// Return the length of the common prefix of two strings
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type RouterAPI struct {
+	Items   ItemList
+	Profile Profile
+}
+
+// What answered the request, and with which IDs
+type Answer struct {
+	Handler string
+	IDs     []string
+}
+
+type ItemList []Item
+
+func (l *ItemList) GET() *Answer {
+	return &Answer{Handler: "ItemList.GET"}
+}
+
+func (l *ItemList) GETLogin() *Answer {
+	return &Answer{Handler: "ItemList.GETLogin"}
+}
+
+type Item struct {
+	Parts PartList
+}
+
+func (i *Item) GET(id *ID) *Answer {
+	return &Answer{Handler: "Item.GET", IDs: []string{id.String()}}
+}
+
+func (i *Item) PUT(id *ID) *Answer {
+	return &Answer{Handler: "Item.PUT", IDs: []string{id.String()}}
+}
+
+type PartList []Part
+
+type Part struct{}
+
+func (p *Part) GET(id *ID) *Answer {
+	return &Answer{Handler: "Part.GET", IDs: []string{id.String()}}
+}
+
+type Profile struct {
+	Settings Settings
+}
+
+func (p *Profile) GET() *Answer {
+	return &Answer{Handler: "Profile.GET"}
+}
+
+func (p *Profile) GETLogin() *Answer {
+	return &Answer{Handler: "Profile.GETLogin"}
+}
+
+type Settings struct{}
+
+func (s *Settings) GET() *Answer {
+	return &Answer{Handler: "Settings.GET"}
+}
+
+func TestRouter(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+
+	tests := []struct {
+		Method  string
+		URI     string
+		Handler string
+		IDs     []string
+	}{
+		{"GET", "/routerapi/items", "ItemList.GET", nil},
+		{"GET", "/routerapi/items/", "ItemList.GET", nil},
+		{"GET", "/routerapi/items?q=1", "ItemList.GET", nil},
+		// Static actions have priority over IDs
+		{"GET", "/routerapi/items/login", "ItemList.GETLogin", nil},
+		// But only for the HTTP method of the action
+		{"PUT", "/routerapi/items/login", "Item.PUT", []string{"login"}},
+		{"GET", "/routerapi/items/7", "Item.GET", []string{"7"}},
+		{"GET", "/routerapi/items/log", "Item.GET", []string{"log"}},
+		{"GET", "/routerapi/items/logins", "Item.GET", []string{"logins"}},
+		{"GET", "/routerapi/items/7/parts/9", "Part.GET", []string{"9"}},
+		{"GET", "/routerapi/profile", "Profile.GET", nil},
+		{"GET", "/routerapi/profile/login", "Profile.GETLogin", nil},
+		{"GET", "/routerapi/profile/settings", "Settings.GET", nil},
+	}
+
+	for _, test := range tests {
+		res := serve(t, route, test.Method, test.URI)
+		if res.Code != http.StatusOK {
+			t.Errorf("[%s] %s: expected status %d, got %d: %s",
+				test.Method, test.URI, http.StatusOK, res.Code, res.Body)
+			continue
+		}
+
+		answer := Answer{}
+		err := json.Unmarshal(res.Body.Bytes(), &answer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if answer.Handler != test.Handler {
+			t.Errorf("[%s] %s: expected handler %s, got %s",
+				test.Method, test.URI, test.Handler, answer.Handler)
+		}
+		if len(answer.IDs) != len(test.IDs) {
+			t.Errorf("[%s] %s: expected IDs %q, got %q",
+				test.Method, test.URI, test.IDs, answer.IDs)
+			continue
+		}
+		for i := range test.IDs {
+			if answer.IDs[i] != test.IDs[i] {
+				t.Errorf("[%s] %s: expected IDs %q, got %q",
+					test.Method, test.URI, test.IDs, answer.IDs)
+			}
+		}
+	}
+
+	notFound := []struct {
+		Method string
+		URI    string
+	}{
+		{"GET", "/other/items"},
+		{"GET", "/routerapi/nothing"},
+		{"DELETE", "/routerapi/items/7"},
+		{"GET", "/routerapi/profile/logout"},
+		{"GET", "/routerapi/profile/settings/7"},
+	}
+
+	for _, test := range notFound {
+		res := serve(t, route, test.Method, test.URI)
+		if res.Code != http.StatusNotFound {
+			t.Errorf("[%s] %s: expected status %d, got %d: %s",
+				test.Method, test.URI, http.StatusNotFound, res.Code, res.Body)
+		}
+	}
+}
+
+func TestRouterCapturesIDs(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+
+	ids := idMap{}
+	h, err := route.handler("/routerapi/items/7/parts/9", "GET", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.Method.Owner.Elem().Name() != "Part" {
+		t.Errorf("Expected a Part handler, got %s", h)
+	}
+
+	expected := map[string]string{"Item": "7", "Part": "9"}
+	for t2, v := range ids {
+		id := v.Interface().(*ID)
+		if expected[t2.Elem().Name()] != id.String() {
+			t.Errorf("Expected ID %s for %s, got %s", expected[t2.Elem().Name()], t2, id)
+		}
+	}
+	if len(ids) != len(expected) {
+		t.Errorf("Expected %d IDs, got %d", len(expected), len(ids))
+	}
+}
+
+func TestRouterLookupDoesNotAllocate(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		ps := params{}
+		route.tree.lookup("/routerapi/items/7/parts/9", "GET", &ps)
+		route.tree.lookup("/routerapi/items/login", "PUT", &ps)
+	})
+
+	if allocs != 0 {
+		t.Errorf("Expected no allocations in the lookup, got %v", allocs)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"reflect"
+	"sort"
+)
+
+// A handler of the Route tree, described for dashboards, audits and generators
+type Endpoint struct {
+	// The path template, with {id} for each ID, like /api/a/bs/{id}
+	Path       string
+	HTTPMethod string
+	Action     string // Empty for the main handler of the Route
+
+	// The Route that answers the requests
+	Route *Route
+
+	// The method that answers, like GETLogin, and the resource it belongs to
+	Method   string
+	Resource reflect.Type
+
+	// The inputs and outputs of the method, without the resource itself
+	Inputs  []reflect.Type
+	Outputs []reflect.Type
+
+	// The dependencies constructed before the method runs, in the order they are constructed
+	Dependencies []Dependency
+
+	Doc Doc
+}
+
+// A dependency constructed for a handler
+type Dependency struct {
+	Type reflect.Type
+
+	// The Type of the Init method that constructs it, with the resource as first input
+	// It is nil when the dependency has no Init method
+	Init reflect.Type
+
+	// The dependencies in the same level can be constructed at the same time
+	Level int
+}
+
+// Call the function for each endpoint of this Route and its children,
+// sorted by path and HTTP method
+// It stops at the first error returned by the function
+func (ro *Route) Walk(fn func(Endpoint) error) error {
+
+	endpoints := []Endpoint{}
+	ro.endpoints(&endpoints)
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].HTTPMethod < endpoints[j].HTTPMethod
+	})
+
+	for _, e := range endpoints {
+		err := fn(e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add the endpoints of this Route and its children to the list
+func (ro *Route) endpoints(list *[]Endpoint) {
+
+	for _, h := range ro.Handlers {
+		*list = append(*list, h.endpoint())
+	}
+
+	if ro.IsSlice && ro.Elem != nil {
+		ro.Elem.endpoints(list)
+	}
+
+	for _, child := range ro.Children {
+		child.endpoints(list)
+	}
+}
+
+// Return the description of the handler
+func (h *handler) endpoint() Endpoint {
+
+	path, _ := h.Route.path(idMap{})
+	if len(h.Method.Name) > 0 {
+		path += "/" + h.Method.Name
+	}
+
+	e := Endpoint{
+		Path:         path,
+		HTTPMethod:   h.Method.HTTPMethod,
+		Action:       h.Method.Name,
+		Route:        h.Route,
+		Method:       h.Method.Method.Name,
+		Resource:     h.Method.Owner,
+		Inputs:       append([]reflect.Type{}, h.Method.Inputs[1:]...),
+		Outputs:      append([]reflect.Type{}, h.Method.Outputs...),
+		Dependencies: []Dependency{},
+		Doc:          h.Doc,
+	}
+
+	if h.Plan != nil {
+		for level, steps := range h.Plan.Levels {
+			for _, s := range steps {
+				d := Dependency{Type: s.Dependency.Value.Type(), Level: level}
+				if s.Dependency.Method != nil {
+					d.Init = s.Dependency.Method.Method.Type
+				}
+				e.Dependencies = append(e.Dependencies, d)
+			}
+		}
+	}
+
+	return e
+}
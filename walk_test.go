@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	route := newTestRoute(t, RouterAPI{})
+
+	expected := []string{
+		"GET /routerapi/items ItemList.GET",
+		"GET /routerapi/items/login ItemList.GETLogin",
+		"GET /routerapi/items/{id} Item.GET",
+		"PUT /routerapi/items/{id} Item.PUT",
+		"GET /routerapi/items/{id}/parts/{id} Part.GET",
+		"GET /routerapi/profile Profile.GET",
+		"GET /routerapi/profile/login Profile.GETLogin",
+		"GET /routerapi/profile/settings Settings.GET",
+	}
+
+	found := []string{}
+	err := route.Walk(func(e Endpoint) error {
+		found = append(found, fmt.Sprintf("%s %s %s.%s", e.HTTPMethod, e.Path, elemOfType(e.Resource).Name(), e.Method))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the endpoints\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+
+	// The walk stops at the first error
+	stop := errors.New("stop")
+	count := 0
+	err = route.Walk(func(e Endpoint) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("Expected the walk to stop at the first endpoint, got %v after %d", err, count)
+	}
+}
+
+func TestWalkDependencies(t *testing.T) {
+	route := newTestRoute(t, ParallelAPI{})
+
+	endpoints := []Endpoint{}
+	route.Walk(func(e Endpoint) error {
+		endpoints = append(endpoints, e)
+		return nil
+	})
+	if len(endpoints) != 1 {
+		t.Fatalf("Expected one endpoint, got %d", len(endpoints))
+	}
+
+	e := endpoints[0]
+	if e.Path != "/parallelapi/report" || e.Action != "" || e.Route != route.Children["report"] {
+		t.Errorf("Unexpected endpoint %+v", e)
+	}
+
+	inputs := []reflect.Type{reflect.TypeOf(PartA{}), reflect.TypeOf(PartB{}), reflect.TypeOf(PartC{}), errorSliceType}
+	if !reflect.DeepEqual(e.Inputs, inputs) {
+		t.Errorf("Expected the inputs %v, got %v", inputs, e.Inputs)
+	}
+	if !reflect.DeepEqual(e.Outputs, []reflect.Type{reflect.TypeOf(&Report{})}) {
+		t.Errorf("Unexpected outputs %v", e.Outputs)
+	}
+
+	found := []string{}
+	for _, d := range e.Dependencies {
+		found = append(found, fmt.Sprintf("%d %s %v", d.Level, d.Type, d.Init))
+	}
+	expected := []string{
+		"0 *api.Report <nil>",
+		"0 *api.PartA func(*api.PartA) error",
+		"0 *api.PartB func(*api.PartB) error",
+		"0 *api.PartC func(*api.PartC)",
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the dependencies\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+}
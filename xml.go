@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// Encodes the responses in XML
+// Maps, like the multiple outputs response, and Slices
+// are written inside a <response> element
+type XMLEncoder struct {
+	Indent string
+}
+
+func (e XMLEncoder) MediaType() string {
+	return "application/xml"
+}
+
+func (e XMLEncoder) Encode(w io.Writer, v interface{}) error {
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", e.Indent)
+
+	err = e.encode(encoder, v)
+	if err != nil {
+		return err
+	}
+
+	return encoder.Flush()
+}
+
+func (e XMLEncoder) encode(encoder *xml.Encoder, v interface{}) error {
+
+	root := xml.StartElement{Name: xml.Name{Local: "response"}}
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+		return encodeXMLMap(encoder, value, root)
+
+	case reflect.Slice, reflect.Array:
+		// Each element will be an element inside the root
+		err := encoder.EncodeToken(root)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			err = encoder.Encode(value.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+
+		return encoder.EncodeToken(root.End())
+	}
+
+	return encoder.Encode(v)
+}
+
+// Encode each entry of the map as an element inside the start element
+// encoding/xml doesn't encode maps, so the inner maps are encoded the same way
+func encodeXMLMap(encoder *xml.Encoder, value reflect.Value, start xml.StartElement) error {
+	err := encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for _, k := range value.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		elem := value.MapIndex(reflect.ValueOf(k).Convert(value.Type().Key()))
+		for elem.Kind() == reflect.Interface || elem.Kind() == reflect.Ptr && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+		if !elem.IsValid() {
+			continue // Nothing to encode, like encoding/xml does with nil values
+		}
+
+		name := xml.StartElement{Name: xml.Name{Local: k}}
+		if elem.Kind() == reflect.Map {
+			err = encodeXMLMap(encoder, elem, name)
+		} else {
+			err = encoder.EncodeElement(elem.Interface(), name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// Decodes the request bodies in XML
+type XMLDecoder struct{}
+
+func (d XMLDecoder) MediaType() string {
+	return "application/xml"
+}
+
+func (d XMLDecoder) Decode(req *http.Request, v interface{}) error {
+	return xml.NewDecoder(req.Body).Decode(v)
+}
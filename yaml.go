@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encodes the responses in YAML
+// It follows the same rules of the JSON responses: json tags, omitempty...
+type YAMLEncoder struct{}
+
+func (e YAMLEncoder) MediaType() string {
+	return "application/yaml"
+}
+
+func (e YAMLEncoder) Encode(w io.Writer, v interface{}) error {
+	generic, err := genericValue(v)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	writeYAML(buf, generic, 0)
+	buf.WriteString("\n")
+	return buf.Flush()
+}
+
+// Write the generic value in YAML
+// Maps and lists start in a new line, indented by the given level
+func writeYAML(w *bufio.Writer, v interface{}, lvl int) {
+	indent := strings.Repeat("  ", lvl)
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			w.WriteString("{}")
+			return
+		}
+
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for i, k := range keys {
+			if i > 0 || lvl > 0 {
+				w.WriteString("\n" + indent)
+			}
+			w.WriteString(yamlString(k) + ":")
+			writeYAMLValue(w, value[k], lvl+1)
+		}
+
+	case []interface{}:
+		if len(value) == 0 {
+			w.WriteString("[]")
+			return
+		}
+
+		for i, elem := range value {
+			if i > 0 || lvl > 0 {
+				w.WriteString("\n" + indent)
+			}
+			w.WriteString("-")
+			writeYAMLValue(w, elem, lvl+1)
+		}
+
+	default:
+		w.WriteString(yamlScalar(v))
+	}
+}
+
+// Write the value of a map entry or a list element
+// Scalars and empty collections stay in the same line
+func writeYAMLValue(w *bufio.Writer, v interface{}, lvl int) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			w.WriteString(" {}")
+			return
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			w.WriteString(" []")
+			return
+		}
+	default:
+		w.WriteString(" " + yamlScalar(v))
+		return
+	}
+	writeYAML(w, v, lvl)
+}
+
+func yamlScalar(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(value)
+	case json.Number:
+		return value.String()
+	case string:
+		return yamlString(value)
+	}
+	return yamlString(fmt.Sprint(v))
+}
+
+// Strings are always double quoted,
+// so they are never read as numbers, booleans or null
+func yamlString(s string) string {
+	return strconv.Quote(s)
+}